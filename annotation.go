@@ -0,0 +1,58 @@
+package recaptcha
+
+import (
+	"context"
+)
+
+// Annotation ground truth about an assessed event, sent back to improve the reCAPTCHA Enterprise model for the site
+type Annotation string
+
+// Annotations accepted by reCAPTCHA Enterprise
+const (
+	AnnotationLegitimate        Annotation = "LEGITIMATE"
+	AnnotationFraudulent        Annotation = "FRAUDULENT"
+	AnnotationPasswordCorrect   Annotation = "PASSWORD_CORRECT"
+	AnnotationPasswordIncorrect Annotation = "PASSWORD_INCORRECT"
+)
+
+// AnnotationReason detail explaining an Annotation
+type AnnotationReason string
+
+// Annotation reasons accepted by reCAPTCHA Enterprise
+const (
+	AnnotationReasonChargeback          AnnotationReason = "CHARGEBACK"
+	AnnotationReasonChargebackFraud     AnnotationReason = "CHARGEBACK_FRAUD"
+	AnnotationReasonChargebackDispute   AnnotationReason = "CHARGEBACK_DISPUTE"
+	AnnotationReasonRefund              AnnotationReason = "REFUND"
+	AnnotationReasonRefundFraud         AnnotationReason = "REFUND_FRAUD"
+	AnnotationReasonTransactionAccepted AnnotationReason = "TRANSACTION_ACCEPTED"
+	AnnotationReasonTransactionDeclined AnnotationReason = "TRANSACTION_DECLINED"
+	AnnotationReasonPaymentHeuristics   AnnotationReason = "PAYMENT_HEURISTICS"
+	AnnotationReasonInitiatedTwoFactor  AnnotationReason = "INITIATED_TWO_FACTOR"
+	AnnotationReasonPassedTwoFactor     AnnotationReason = "PASSED_TWO_FACTOR"
+	AnnotationReasonFailedTwoFactor     AnnotationReason = "FAILED_TWO_FACTOR"
+	AnnotationReasonCorrectPassword     AnnotationReason = "CORRECT_PASSWORD"
+	AnnotationReasonIncorrectPassword   AnnotationReason = "INCORRECT_PASSWORD"
+	AnnotationReasonSocialSpam          AnnotationReason = "SOCIAL_SPAM"
+)
+
+type annotateRequest struct {
+	Annotation Annotation         `json:"annotation,omitempty"`
+	Reasons    []AnnotationReason `json:"reasons,omitempty"`
+}
+
+// Annotate sends ground truth about the assessment named assessmentName, as found in VerifyResult.AssessmentName.
+// Like verification failures to reach the api are reported as an *Error with RequestError set
+func (e *Enterprise) Annotate(assessmentName string, annotation Annotation, reasons ...AnnotationReason) error {
+	return e.AnnotateContext(context.Background(), assessmentName, annotation, reasons...)
+}
+
+// AnnotateContext is like Annotate but the request to reCAPTCHA Enterprise is bound to ctx
+func (e *Enterprise) AnnotateContext(ctx context.Context, assessmentName string, annotation Annotation, reasons ...AnnotationReason) error {
+	if assessmentName == "" {
+		return &Error{msg: "assessment name cannot be blank", Reason: ReasonInvalidArgument}
+	}
+	body := annotateRequest{Annotation: annotation, Reasons: reasons}
+	var result struct{}
+	return postJSON(ctx, e.client, "recaptcha enterprise", e.endpoint("/"+assessmentName+":annotate"), body, &result)
+}
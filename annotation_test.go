@@ -0,0 +1,50 @@
+package recaptcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "gopkg.in/check.v1"
+)
+
+type AnnotationSuite struct{}
+
+var _ = Suite(&AnnotationSuite{})
+
+func (s *AnnotationSuite) TestAnnotate(c *C) {
+	client := &mockStatusClient{status: 200, body: `{}`}
+	captcha := Enterprise{
+		client:         client,
+		APIKey:         "my api key",
+		EnterpriseLink: enterpriseLink,
+	}
+
+	err := captcha.Annotate("projects/my-project/assessments/b0bd9e6a", AnnotationFraudulent, AnnotationReasonChargeback, AnnotationReasonRefundFraud)
+	c.Assert(err, IsNil)
+	c.Check(client.request.URL.String(), Equals, enterpriseLink+"/projects/my-project/assessments/b0bd9e6a:annotate?key=my+api+key")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(client.request.Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"annotation": "FRAUDULENT",
+		"reasons":    []interface{}{"CHARGEBACK", "REFUND_FRAUD"},
+	})
+
+	err = captcha.Annotate("", AnnotationLegitimate)
+	c.Check(err, ErrorMatches, "assessment name cannot be blank")
+	c.Check(errors.Is(err, ErrInvalidArgument), Equals, true)
+	c.Check(err.(*Error).RequestError, Equals, false)
+
+	client.status, client.body = 404, `{"error": {"code": 404, "message": "Assessment not found."}}`
+	err = captcha.Annotate("projects/my-project/assessments/missing", AnnotationLegitimate)
+	c.Assert(err, NotNil)
+	c.Check(err.(*Error).RequestError, Equals, true)
+	c.Check(err, ErrorMatches, "recaptcha enterprise endpoint answered with status 404: 'Assessment not found.'")
+
+	captcha.client = &mockContextClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = captcha.AnnotateContext(ctx, "projects/my-project/assessments/b0bd9e6a", AnnotationPasswordCorrect)
+	c.Check(errors.Is(err, context.Canceled), Equals, true)
+	c.Check(err.(*Error).RequestError, Equals, true)
+}
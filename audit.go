@@ -0,0 +1,235 @@
+package recaptcha
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditRecord describes a verification decision, records hold no secret nor raw challenge response
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// TokenHash is the hex encoded sha256 of the challenge response.
+	TokenHash string  `json:"token_hash"`
+	RemoteIP  string  `json:"remote_ip,omitempty"`
+	Version   string  `json:"version"`
+	Action    string  `json:"action,omitempty"`
+	Hostname  string  `json:"hostname,omitempty"`
+	Score     float32 `json:"score"`
+	// Policy is the action pattern of the ActionPolicy applied, if any.
+	Policy string `json:"policy,omitempty"`
+	// Checks holds the thresholds and other options checked along with their outcome.
+	Checks   []CheckResult `json:"checks,omitempty"`
+	Decision string        `json:"decision"`
+	Reason   string        `json:"reason"`
+	// ShadowReason is the reason the decision would have been a failure for without shadow mode.
+	ShadowReason string   `json:"shadow_reason,omitempty"`
+	FailedOpen   bool     `json:"failed_open,omitempty"`
+	ErrorCodes   []string `json:"error_codes,omitempty"`
+}
+
+// AuditSink receives a record of every verification decision.
+// Audit is called synchronously so it must not block and be safe for concurrent use
+type AuditSink interface {
+	Audit(record AuditRecord)
+}
+
+// audit sends the record of a verification to r.Audit
+func (r *ReCAPTCHA) audit(challengeResponse string, options VerifyOption, result VerifyResult, err error) {
+	record := AuditRecord{
+		Time:       time.Now().UTC(),
+		TokenHash:  hashToken(challengeResponse),
+		RemoteIP:   options.RemoteIP,
+		Version:    r.Version.String(),
+		Action:     result.Action,
+		Hostname:   result.Hostname,
+		Score:      result.Score,
+		Checks:     result.Checks,
+		Decision:   result.Decision.String(),
+		Reason:     reasonOf(err).String(),
+		FailedOpen: result.FailedOpen,
+		ErrorCodes: result.ErrorCodes,
+	}
+	if result.Policy != nil {
+		record.Policy = result.Policy.Action
+	}
+	if result.ShadowError != nil {
+		record.ShadowReason = reasonOf(result.ShadowError).String()
+	}
+	if result.FailedOpen {
+		record.Reason = reasonOf(result.OutageError).String()
+	}
+	r.Audit.Audit(record)
+}
+
+// FileAuditOptions configures a FileAudit, the zero value uses the defaults below
+type FileAuditOptions struct {
+	// MaxBytes rotates the file before it grows past this size, 100MB when zero.
+	MaxBytes int64
+	// MaxBackups is the number of rotated files kept, named after the file with a .1, .2, ... suffix, 5 when zero.
+	MaxBackups int
+	// BufferSize is the number of records queued for writing, records are dropped when it is full, 1024 when zero.
+	BufferSize int
+	// OnError is called with write and rotation errors, from the writing goroutine.
+	OnError func(err error)
+}
+
+// FileAudit is an AuditSink writing records as JSON Lines to a rotating file.
+// Records are queued and written by a background goroutine so auditing never blocks verification,
+// call Close to write the queued records before exiting
+type FileAudit struct {
+	path    string
+	options FileAuditOptions
+	records chan AuditRecord
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+}
+
+// NewFileAudit opens, or creates, the file at path and starts writing the records audited to it
+func NewFileAudit(path string, options FileAuditOptions) (*FileAudit, error) {
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = 100 << 20
+	}
+	if options.MaxBackups <= 0 {
+		options.MaxBackups = 5
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 1024
+	}
+	a := &FileAudit{
+		path:    path,
+		options: options,
+		records: make(chan AuditRecord, options.BufferSize),
+		done:    make(chan struct{}),
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	go a.run()
+	return a, nil
+}
+
+// Audit queues record for writing, it is dropped when the queue is full or the FileAudit closed
+func (a *FileAudit) Audit(record AuditRecord) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		atomic.AddUint64(&a.dropped, 1)
+		return
+	}
+	select {
+	case a.records <- record:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// Dropped returns the number of records dropped so far
+func (a *FileAudit) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Close writes the queued records and closes the file, later records are dropped
+func (a *FileAudit) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.records)
+	a.mu.Unlock()
+	<-a.done
+	if a.file == nil {
+		return nil
+	}
+	if err := a.writer.Flush(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
+
+func (a *FileAudit) run() {
+	defer close(a.done)
+	for record := range a.records {
+		if err := a.write(record); err != nil && a.options.OnError != nil {
+			a.options.OnError(err)
+		}
+		if len(a.records) == 0 && a.writer != nil {
+			if err := a.writer.Flush(); err != nil && a.options.OnError != nil {
+				a.options.OnError(err)
+			}
+		}
+	}
+}
+
+func (a *FileAudit) write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	var rotateErr error
+	if a.size > 0 && a.size+int64(len(line)) > a.options.MaxBytes {
+		rotateErr = a.rotate()
+	}
+	// reopen the file a failed rotation left closed, the record is kept in the current file
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	n, err := a.writer.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+func (a *FileAudit) open() error {
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("couldn't open audit file: '%s'", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("couldn't open audit file: '%s'", err)
+	}
+	a.file, a.size = file, info.Size()
+	a.writer = bufio.NewWriter(file)
+	return nil
+}
+
+// rotate shifts the backups, dropping the oldest one, moves the current file to the first backup and opens a new one
+func (a *FileAudit) rotate() error {
+	if err := a.writer.Flush(); err != nil {
+		return err
+	}
+	err := a.file.Close()
+	a.file, a.writer = nil, nil
+	if err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", a.path, a.options.MaxBackups))
+	for i := a.options.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return fmt.Errorf("couldn't rotate audit file: '%s'", err)
+	}
+	return a.open()
+}
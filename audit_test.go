@@ -0,0 +1,133 @@
+package recaptcha
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type AuditSuite struct{}
+
+var _ = Suite(&AuditSuite{})
+
+type mockAuditSink struct {
+	records []AuditRecord
+}
+
+func (m *mockAuditSink) Audit(record AuditRecord) {
+	m.records = append(m.records, record)
+}
+
+func readAuditFile(c *C, path string) []AuditRecord {
+	file, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer file.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		c.Assert(json.Unmarshal(scanner.Bytes(), &record), IsNil)
+		records = append(records, record)
+	}
+	return records
+}
+
+func (s *AuditSuite) TestAudit(c *C) {
+	sink := &mockAuditSink{}
+	captcha := ReCAPTCHA{
+		client:   &mockBodyClient{body: v3Body("0.3")},
+		Version:  V3,
+		Policies: PolicyTable{{Action: "comment", Threshold: 0.5}},
+		Audit:    sink,
+	}
+	_, err := captcha.Check("my token", VerifyOption{RemoteIP: "192.0.2.1"})
+	c.Check(err, NotNil)
+	c.Assert(sink.records, HasLen, 1)
+	record := sink.records[0]
+	c.Check(record.TokenHash, Equals, hashToken("my token"))
+	c.Check(record.RemoteIP, Equals, "192.0.2.1")
+	c.Check(record.Version, Equals, "v3")
+	c.Check(record.Action, Equals, "comment")
+	c.Check(record.Score, Equals, float32(0.3))
+	c.Check(record.Policy, Equals, "comment")
+	c.Check(record.Checks, DeepEquals, []CheckResult{{Name: CheckThreshold, Passed: false, Expected: "0.500000", Received: "0.300000"}})
+	c.Check(record.Decision, Equals, "deny")
+	c.Check(record.Reason, Equals, "score_below_threshold")
+	c.Check(record.Time.IsZero(), Equals, false)
+
+	captcha.client = &mockSequenceClient{statuses: []int{503}}
+	captcha.Outage = &OutagePolicy{Mode: FailOpen}
+	_, err = captcha.Check("my token", VerifyOption{})
+	c.Check(err, IsNil)
+	c.Assert(sink.records, HasLen, 2)
+	c.Check(sink.records[1].FailedOpen, Equals, true)
+	c.Check(sink.records[1].Decision, Equals, "review")
+	c.Check(sink.records[1].Reason, Equals, "transport")
+}
+
+func (s *AuditSuite) TestFileAudit(c *C) {
+	path := filepath.Join(c.MkDir(), "audit.jsonl")
+	audit, err := NewFileAudit(path, FileAuditOptions{})
+	c.Assert(err, IsNil)
+	audit.Audit(AuditRecord{TokenHash: "a", Decision: "allow", Reason: "none"})
+	audit.Audit(AuditRecord{TokenHash: "b", Decision: "deny", Reason: "invalid_solution", ErrorCodes: []string{"invalid-input-response"}})
+	c.Assert(audit.Close(), IsNil)
+	c.Assert(audit.Close(), IsNil)
+	audit.Audit(AuditRecord{TokenHash: "c"})
+	c.Check(audit.Dropped(), Equals, uint64(1))
+
+	records := readAuditFile(c, path)
+	c.Assert(records, HasLen, 2)
+	c.Check(records[0].TokenHash, Equals, "a")
+	c.Check(records[1].ErrorCodes, DeepEquals, []string{"invalid-input-response"})
+
+	content, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Check(strings.Count(string(content), "\n"), Equals, 2)
+}
+
+func (s *AuditSuite) TestFileAuditRotation(c *C) {
+	path := filepath.Join(c.MkDir(), "audit.jsonl")
+	audit, err := NewFileAudit(path, FileAuditOptions{MaxBytes: 150, MaxBackups: 2})
+	c.Assert(err, IsNil)
+	for _, hash := range []string{"a", "b", "c", "d", "e"} {
+		audit.Audit(AuditRecord{TokenHash: hash})
+	}
+	c.Assert(audit.Close(), IsNil)
+
+	c.Check(readAuditFile(c, path), HasLen, 1)
+	c.Check(readAuditFile(c, path+".1"), HasLen, 1)
+	c.Check(readAuditFile(c, path+".2"), HasLen, 1)
+	_, err = os.Stat(path + ".3")
+	c.Check(os.IsNotExist(err), Equals, true)
+	c.Check(readAuditFile(c, path)[0].TokenHash, Equals, "e")
+}
+
+func (s *AuditSuite) TestFileAuditRotationFailure(c *C) {
+	path := filepath.Join(c.MkDir(), "audit.jsonl")
+	// a non empty directory in place of the backup makes the rotation fail
+	c.Assert(os.Mkdir(path+".1", 0700), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(path+".1", "blocker"), nil, 0600), IsNil)
+	errs := make(chan error, 10)
+	audit, err := NewFileAudit(path, FileAuditOptions{MaxBytes: 150, MaxBackups: 1, OnError: func(err error) { errs <- err }})
+	c.Assert(err, IsNil)
+	audit.Audit(AuditRecord{TokenHash: "a"})
+	audit.Audit(AuditRecord{TokenHash: "b"})
+	c.Check(<-errs, ErrorMatches, "couldn't rotate audit file: .*")
+
+	c.Assert(os.RemoveAll(path+".1"), IsNil)
+	audit.Audit(AuditRecord{TokenHash: "c"})
+	c.Assert(audit.Close(), IsNil)
+	c.Check(errs, HasLen, 0)
+
+	backup := readAuditFile(c, path+".1")
+	c.Assert(backup, HasLen, 2)
+	c.Check(backup[1].TokenHash, Equals, "b")
+	records := readAuditFile(c, path)
+	c.Assert(records, HasLen, 1)
+	c.Check(records[0].TokenHash, Equals, "c")
+}
@@ -0,0 +1,173 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BreakerState state of a CircuitBreaker
+type BreakerState int8
+
+const (
+	// BreakerClosed requests reach recaptcha
+	BreakerClosed BreakerState = iota
+	// BreakerOpen requests fail right away with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen a single probe request reaches recaptcha, the others fail with ErrCircuitOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops calling recaptcha when too many requests fail so callers do not wait for timeouts of a degraded endpoint.
+// While open, verification fails with a RequestError of reason ReasonCircuitOpen, subject to the OutagePolicy.
+// The zero value uses the defaults below, it must not be copied once used
+type CircuitBreaker struct {
+	// FailureRatio opens the breaker when reached by the share of failed requests within Window, 0.5 when zero.
+	FailureRatio float64
+	// MinRequests is the number of requests within Window needed before the ratio is considered, 10 when zero.
+	MinRequests int
+	// Window is the period requests are counted over, 10s when zero.
+	Window time.Duration
+	// CoolDown is how long the breaker stays open before letting a probe through, 30s when zero.
+	CoolDown time.Duration
+	// OnStateChange is called after every transition, for instance to feed metrics.
+	OnStateChange func(from, to BreakerState)
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	now         func() time.Time
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.clock().Sub(b.openedAt) >= b.coolDown() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow returns ErrCircuitOpen as an *Error when the request must not reach recaptcha, a nil breaker allows everything
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerOpen && b.clock().Sub(b.openedAt) >= b.coolDown() {
+		b.state = BreakerHalfOpen
+	}
+	allowed := b.state == BreakerClosed || (b.state == BreakerHalfOpen && !b.probing)
+	if b.state == BreakerHalfOpen && allowed {
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	if !allowed {
+		return &Error{msg: "circuit breaker open, recaptcha endpoint not called", RequestError: true, Reason: ReasonCircuitOpen}
+	}
+	return nil
+}
+
+// record accounts for the outcome of a request allowed by allow,
+// canceled requests tell nothing about the endpoint and are ignored
+func (b *CircuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	var recaptchaErr *Error
+	failed := errors.As(err, &recaptchaErr) && recaptchaErr.RequestError
+	ignored := errors.Is(err, context.Canceled)
+
+	b.mu.Lock()
+	from := b.state
+	now := b.clock()
+	switch {
+	case b.state == BreakerHalfOpen:
+		b.probing = false
+		if ignored {
+			break
+		}
+		if failed {
+			b.state, b.openedAt = BreakerOpen, now
+		} else {
+			b.state = BreakerClosed
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	case b.state == BreakerClosed && !ignored:
+		if now.Sub(b.windowStart) >= b.window() {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.minRequests() && float64(b.failures) >= b.failureRatio()*float64(b.requests) {
+			b.state, b.openedAt = BreakerOpen, now
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio <= 0 {
+		return 0.5
+	}
+	return b.FailureRatio
+}
+
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests <= 0 {
+		return 10
+	}
+	return b.MinRequests
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window <= 0 {
+		return 10 * time.Second
+	}
+	return b.Window
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return 30 * time.Second
+	}
+	return b.CoolDown
+}
@@ -0,0 +1,95 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type BreakerSuite struct{}
+
+var _ = Suite(&BreakerSuite{})
+
+func (s *BreakerSuite) TestStates(c *C) {
+	now := time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	var transitions []string
+	breaker := &CircuitBreaker{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		CoolDown:     time.Minute,
+		OnStateChange: func(from, to BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+		now: func() time.Time { return now },
+	}
+	failure := &Error{RequestError: true, Reason: ReasonTransport}
+
+	for _, err := range []error{nil, failure, nil} {
+		c.Assert(breaker.allow(), IsNil)
+		breaker.record(err)
+	}
+	c.Check(breaker.State(), Equals, BreakerClosed)
+	// canceled requests are not counted
+	breaker.record(&Error{RequestError: true, Reason: ReasonTransport, err: context.Canceled})
+	c.Check(breaker.State(), Equals, BreakerClosed)
+	breaker.record(failure)
+	c.Check(breaker.State(), Equals, BreakerOpen)
+
+	err := breaker.allow()
+	c.Check(errors.Is(err, ErrCircuitOpen), Equals, true)
+	c.Check(err.(*Error).RequestError, Equals, true)
+
+	// a single probe after the cool down, failing reopens
+	now = now.Add(time.Minute)
+	c.Check(breaker.State(), Equals, BreakerHalfOpen)
+	c.Assert(breaker.allow(), IsNil)
+	c.Check(errors.Is(breaker.allow(), ErrCircuitOpen), Equals, true)
+	breaker.record(failure)
+	c.Check(breaker.State(), Equals, BreakerOpen)
+
+	// a successful probe closes
+	now = now.Add(time.Minute)
+	c.Assert(breaker.allow(), IsNil)
+	breaker.record(nil)
+	c.Check(breaker.State(), Equals, BreakerClosed)
+	c.Check(breaker.allow(), IsNil)
+
+	c.Check(transitions, DeepEquals, []string{
+		"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+	})
+}
+
+func (s *BreakerSuite) TestWindow(c *C) {
+	now := time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	breaker := &CircuitBreaker{MinRequests: 2, Window: time.Second, now: func() time.Time { return now }}
+	failure := &Error{RequestError: true, Reason: ReasonTransport}
+	breaker.record(failure)
+	now = now.Add(time.Second)
+	breaker.record(nil)
+	breaker.record(nil)
+	c.Check(breaker.State(), Equals, BreakerClosed)
+}
+
+func (s *BreakerSuite) TestShortCircuit(c *C) {
+	client := &mockSequenceClient{statuses: []int{503}}
+	captcha := ReCAPTCHA{
+		client:  client,
+		Breaker: &CircuitBreaker{MinRequests: 2},
+		Retry:   &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	}
+	err := captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrCircuitOpen), Equals, true)
+	c.Check(client.calls, Equals, 2)
+
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrCircuitOpen), Equals, true)
+	c.Check(client.calls, Equals, 2)
+
+	captcha.Outage = &OutagePolicy{Mode: FailOpen}
+	result, err := captcha.Check("mycode", VerifyOption{})
+	c.Assert(err, IsNil)
+	c.Check(result.FailedOpen, Equals, true)
+	c.Check(errors.Is(result.OutageError, ErrCircuitOpen), Equals, true)
+}
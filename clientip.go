@@ -0,0 +1,118 @@
+package recaptcha
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPResolver finds the IP of the client behind a request, the `Forwarded`, `X-Forwarded-For` and `X-Real-IP`
+// headers are only honored for hops sent by one of the trusted proxies so they cannot be spoofed by clients.
+// A nil *ClientIPResolver trusts no proxy and always returns the address of the direct peer
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver returns a resolver trusting the given proxies, each one being a CIDR like "10.0.0.0/8" or a single IP
+func NewClientIPResolver(trustedProxies ...string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s'", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			resolver.trusted = append(resolver.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %s", proxy, err)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+	return resolver, nil
+}
+
+// Resolve returns the client IP of req. Starting from the direct peer it walks the forwarding chain backwards
+// as long as the hops are trusted proxies and returns the first untrusted address.
+// The `Forwarded` header is preferred over `X-Forwarded-For`, `X-Real-IP` is only used when neither is present
+func (c *ClientIPResolver) Resolve(req *http.Request) string {
+	peer := parseHost(req.RemoteAddr)
+	if peer == nil {
+		return remoteIP(req)
+	}
+	if !c.isTrusted(peer) {
+		return peer.String()
+	}
+	hops := forwardedFor(req.Header)
+	if len(hops) == 0 {
+		hops = splitList(req.Header.Values("X-Forwarded-For"))
+	}
+	if len(hops) == 0 {
+		hops = splitList(req.Header.Values("X-Real-IP"))
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseHost(hops[i])
+		if hop == nil {
+			// obfuscated or garbage hop, the last trusted proxy is the best we know
+			break
+		}
+		peer = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return peer.String()
+}
+
+func (c *ClientIPResolver) isTrusted(ip net.IP) bool {
+	if c == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the `for` parameters of the RFC 7239 `Forwarded` headers, in hop order
+func forwardedFor(header http.Header) []string {
+	var hops []string
+	for _, element := range splitList(header.Values("Forwarded")) {
+		for _, pair := range strings.Split(element, ";") {
+			pair = strings.TrimSpace(pair)
+			if len(pair) > 4 && strings.EqualFold(pair[:4], "for=") {
+				hops = append(hops, strings.Trim(pair[4:], `"`))
+			}
+		}
+	}
+	return hops
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseHost parses an IP optionally followed by a port, IPv6 addresses with a port being enclosed in brackets
+func parseHost(host string) net.IP {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
@@ -0,0 +1,84 @@
+package recaptcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "gopkg.in/check.v1"
+)
+
+type ClientIPSuite struct{}
+
+var _ = Suite(&ClientIPSuite{})
+
+func requestFrom(remoteAddr string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func (s *ClientIPSuite) TestNewClientIPResolver(c *C) {
+	_, err := NewClientIPResolver("10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "::1")
+	c.Check(err, IsNil)
+	_, err = NewClientIPResolver("10.0.0.0/33")
+	c.Check(err, ErrorMatches, "invalid trusted proxy '10.0.0.0/33'.*")
+	_, err = NewClientIPResolver("proxy.local")
+	c.Check(err, ErrorMatches, "invalid trusted proxy 'proxy.local'")
+}
+
+func (s *ClientIPSuite) TestResolve(c *C) {
+	resolver, err := NewClientIPResolver("10.0.0.0/8", "2001:db8::1")
+	c.Assert(err, IsNil)
+
+	cases := []struct {
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		// untrusted peers cannot forge headers
+		{"1.2.3.4:1234", map[string]string{"X-Forwarded-For": "5.6.7.8"}, "1.2.3.4"},
+		{"1.2.3.4:1234", map[string]string{"X-Real-IP": "5.6.7.8"}, "1.2.3.4"},
+		// trusted peer without headers
+		{"10.0.0.1:1234", nil, "10.0.0.1"},
+		// the first untrusted hop from the right is the client
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 5.6.7.8, 10.0.0.2"}, "5.6.7.8"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"10.0.0.1:1234", map[string]string{"X-Real-IP": "5.6.7.8"}, "5.6.7.8"},
+		// Forwarded is preferred over X-Forwarded-For
+		{"10.0.0.1:1234", map[string]string{
+			"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.2`,
+			"X-Forwarded-For": "7.7.7.7",
+		}, "2001:db8:cafe::17"},
+		{"[2001:db8::1]:443", map[string]string{"Forwarded": `For=5.6.7.8:1234;by=10.0.0.1`}, "5.6.7.8"},
+		// obfuscated hops stop the walk on the last trusted proxy
+		{"10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden, for=10.0.0.2"}, "10.0.0.2"},
+	}
+	for _, t := range cases {
+		c.Check(resolver.Resolve(requestFrom(t.remoteAddr, t.headers)), Equals, t.expected, Commentf("%v", t))
+	}
+
+	var none *ClientIPResolver
+	c.Check(none.Resolve(requestFrom("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "5.6.7.8"})), Equals, "10.0.0.1")
+	c.Check(none.Resolve(requestFrom("pipe", nil)), Equals, "pipe")
+}
+
+func (s *ClientIPSuite) TestVerifyRequestWithClientIP(c *C) {
+	client := &mockContextClient{}
+	resolver, err := NewClientIPResolver("10.0.0.0/8")
+	c.Assert(err, IsNil)
+	captcha := ReCAPTCHA{
+		client:   client,
+		ClientIP: resolver,
+	}
+
+	req := formRequest(url.Values{DefaultTokenField: {"mycode"}})
+	req.RemoteAddr = "10.0.0.1:4242"
+	req.Header.Set("X-Forwarded-For", "123.123.123.123")
+	c.Assert(captcha.VerifyRequest(req, VerifyOption{}), IsNil)
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "123.123.123.123")
+}
@@ -0,0 +1,220 @@
+// Command recaptcha-verify verifies a challenge response against recaptcha and prints the decoded answer,
+// the outcome of every option check and the decision, to debug failing verifications without writing code.
+//
+//	recaptcha-verify -secret-file /etc/recaptcha/secret -version v3 -action login -threshold 0.7 <token>
+//
+// The secret is read from -secret, -secret-file or the RECAPTCHA_SECRET environment variable, in that order,
+// and the token from -token, the first argument or standard input when it is "-".
+//
+// Exit codes:
+//
+//	0 the challenge response passed verification
+//	1 the challenge response was rejected
+//	2 invalid usage
+//	3 recaptcha could not be reached or answered garbage
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ezzarghili/recaptcha-go.v4"
+)
+
+// SecretEnv environment variable holding the secret
+const SecretEnv = "RECAPTCHA_SECRET"
+
+// Exit codes
+const (
+	exitPassed   = 0
+	exitRejected = 1
+	exitUsage    = 2
+	exitRequest  = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// output json document printed with -json
+type output struct {
+	Success        bool                    `json:"success"`
+	ChallengeTS    time.Time               `json:"challenge_ts"`
+	Hostname       string                  `json:"hostname,omitempty"`
+	ApkPackageName string                  `json:"apk_package_name,omitempty"`
+	Action         string                  `json:"action,omitempty"`
+	Score          float32                 `json:"score"`
+	ErrorCodes     []string                `json:"error_codes,omitempty"`
+	Attempts       int                     `json:"attempts"`
+	Policy         string                  `json:"policy,omitempty"`
+	Checks         []recaptcha.CheckResult `json:"checks,omitempty"`
+	Decision       string                  `json:"decision"`
+	Reason         string                  `json:"reason"`
+	Error          string                  `json:"error,omitempty"`
+	StatusCode     int                     `json:"status_code,omitempty"`
+}
+
+func run(args []string, getenv func(string) string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("recaptcha-verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		secret     = flags.String("secret", "", "recaptcha secret, prefer -secret-file or "+SecretEnv+" as flags are visible to other users")
+		secretFile = flags.String("secret-file", "", "file holding the recaptcha secret")
+		token      = flags.String("token", "", "challenge response to verify, also read from the first argument, \"-\" reads standard input")
+		version    = flags.String("version", "v3", "recaptcha api version, v2 or v3")
+		link       = flags.String("link", "", "siteverify url, the google endpoint when empty")
+		timeout    = flags.Duration("timeout", 10*time.Second, "request timeout")
+		asJSON     = flags.Bool("json", false, "print the result as json")
+		options    recaptcha.VerifyOption
+		threshold  float64
+	)
+	flags.Float64Var(&threshold, "threshold", 0, "minimum score, v3 only, 0.5 when zero")
+	flags.StringVar(&options.Action, "action", "", "expected action, v3 only")
+	flags.StringVar(&options.Hostname, "hostname", "", "expected hostname")
+	flags.StringVar(&options.ApkPackageName, "apk-package-name", "", "expected android package name")
+	flags.DurationVar(&options.ResponseTime, "response-time", 0, "maximum time since the challenge was solved")
+	flags.StringVar(&options.RemoteIP, "remote-ip", "", "ip of the user who solved the challenge")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	options.Threshold = float32(threshold)
+
+	usage := func(format string, a ...interface{}) int {
+		fmt.Fprintf(stderr, "recaptcha-verify: "+format+"\n", a...)
+		return exitUsage
+	}
+	rest := flags.Args()
+	if *token == "" && len(rest) > 0 {
+		*token, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return usage("unexpected arguments %q, flags must precede the token", rest)
+	}
+	if *token == "-" {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return usage("couldn't read token: %s", err)
+		}
+		*token = strings.TrimSpace(string(content))
+	}
+	if *token == "" {
+		return usage("a token is required")
+	}
+	if *secret == "" && *secretFile != "" {
+		content, err := os.ReadFile(*secretFile)
+		if err != nil {
+			return usage("couldn't read secret: %s", err)
+		}
+		*secret = strings.TrimSpace(string(content))
+	}
+	if *secret == "" {
+		*secret = getenv(SecretEnv)
+	}
+	var apiVersion recaptcha.VERSION
+	switch *version {
+	case "v2":
+		apiVersion = recaptcha.V2
+	case "v3":
+		apiVersion = recaptcha.V3
+	default:
+		return usage("unknown version %q, expecting v2 or v3", *version)
+	}
+
+	captcha, err := recaptcha.NewReCAPTCHA(*secret, apiVersion, *timeout)
+	if err != nil {
+		return usage("%s, set -secret, -secret-file or %s", err, SecretEnv)
+	}
+	if *link != "" {
+		captcha.ReCAPTCHALink = *link
+	}
+	result, err := captcha.CheckContext(context.Background(), *token, options)
+
+	out := output{
+		Success:        result.Success,
+		ChallengeTS:    result.ChallengeTS,
+		Hostname:       result.Hostname,
+		ApkPackageName: result.ApkPackageName,
+		Action:         result.Action,
+		Score:          result.Score,
+		ErrorCodes:     result.ErrorCodes,
+		Attempts:       result.Attempts,
+		Checks:         result.Checks,
+		Decision:       result.Decision.String(),
+		Reason:         recaptcha.ReasonNone.String(),
+	}
+	if result.Policy != nil {
+		out.Policy = result.Policy.Action
+	}
+	code := exitPassed
+	var recaptchaErr *recaptcha.Error
+	if errors.As(err, &recaptchaErr) {
+		out.Error, out.Reason, out.StatusCode = recaptchaErr.Error(), recaptchaErr.Reason.String(), recaptchaErr.StatusCode
+		code = exitRejected
+		if recaptchaErr.RequestError {
+			code = exitRequest
+		}
+	} else if err != nil {
+		out.Error, code = err.Error(), exitRequest
+	}
+	if code == exitRequest {
+		// recaptcha did not answer, there is no decision
+		out.Decision = "unknown"
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(out)
+	} else {
+		printText(stdout, out)
+	}
+	return code
+}
+
+func printText(w io.Writer, out output) {
+	fmt.Fprintf(w, "decision:         %s\n", out.Decision)
+	if out.Error != "" {
+		fmt.Fprintf(w, "error:            %s (%s)\n", out.Error, out.Reason)
+	}
+	if out.StatusCode != 0 {
+		fmt.Fprintf(w, "status code:      %d\n", out.StatusCode)
+	}
+	fmt.Fprintf(w, "success:          %t\n", out.Success)
+	if !out.ChallengeTS.IsZero() {
+		fmt.Fprintf(w, "challenge ts:     %s\n", out.ChallengeTS.Format(time.RFC3339))
+	}
+	if out.Hostname != "" {
+		fmt.Fprintf(w, "hostname:         %s\n", out.Hostname)
+	}
+	if out.ApkPackageName != "" {
+		fmt.Fprintf(w, "apk package name: %s\n", out.ApkPackageName)
+	}
+	if out.Action != "" {
+		fmt.Fprintf(w, "action:           %s\n", out.Action)
+	}
+	if out.Action != "" || out.Score != 0 {
+		fmt.Fprintf(w, "score:            %s\n", strconv.FormatFloat(float64(out.Score), 'f', -1, 32))
+	}
+	if len(out.ErrorCodes) > 0 {
+		fmt.Fprintf(w, "error codes:      %s\n", strings.Join(out.ErrorCodes, ", "))
+	}
+	if out.Policy != "" {
+		fmt.Fprintf(w, "policy:           %s\n", out.Policy)
+	}
+	fmt.Fprintf(w, "attempts:         %d\n", out.Attempts)
+	for _, check := range out.Checks {
+		status := "passed"
+		if !check.Passed {
+			status = "FAILED"
+		}
+		fmt.Fprintf(w, "check %-16s %s, expected %s, received %s\n", check.Name+":", status, check.Expected, check.Received)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/ezzarghili/recaptcha-go.v4/recaptchatest"
+)
+
+func TestPackage(t *testing.T) { TestingT(t) }
+
+type MainSuite struct {
+	server *recaptchatest.Server
+	env    map[string]string
+}
+
+var _ = Suite(&MainSuite{})
+
+func (s *MainSuite) SetUpTest(c *C) {
+	s.server = recaptchatest.NewServer()
+	s.server.Script("human", recaptchatest.Reply{Success: true, Score: 0.9, Action: "login", Hostname: "test.com"})
+	s.server.Script("bot", recaptchatest.Reply{Success: true, Score: 0.1, Action: "login", Hostname: "test.com"})
+	s.server.Script("down", recaptchatest.Reply{Status: 503})
+	s.env = map[string]string{SecretEnv: "env secret"}
+}
+
+func (s *MainSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *MainSuite) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-link", s.server.URL}, args...)
+	code := run(args, func(key string) string { return s.env[key] }, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func (s *MainSuite) TestPassed(c *C) {
+	code, stdout, _ := s.run("", "-action", "login", "-hostname", "test.com", "human")
+	c.Check(code, Equals, exitPassed)
+	c.Check(stdout, Matches, "(?s)decision: +allow\n.*action: +login\nscore: +0.9\n.*check hostname: +passed, expected test.com, received test.com\n.*")
+	requests := s.server.Requests()
+	c.Assert(requests, HasLen, 1)
+	c.Check(requests[0].Secret, Equals, "env secret")
+	c.Check(requests[0].Response, Equals, "human")
+}
+
+func (s *MainSuite) TestRejectedJSON(c *C) {
+	code, stdout, _ := s.run("", "-json", "-threshold", "0.5", "-token", "bot")
+	c.Check(code, Equals, exitRejected)
+	var out output
+	c.Assert(json.Unmarshal([]byte(stdout), &out), IsNil)
+	c.Check(out.Success, Equals, true)
+	c.Check(out.Score, Equals, float32(0.1))
+	c.Check(out.Decision, Equals, "deny")
+	c.Check(out.Reason, Equals, "score_below_threshold")
+	c.Check(out.Checks, HasLen, 1)
+
+	code, stdout, _ = s.run("", "-json", "unknown")
+	c.Check(code, Equals, exitRejected)
+	c.Assert(json.Unmarshal([]byte(stdout), &out), IsNil)
+	c.Check(out.ErrorCodes, DeepEquals, []string{"invalid-input-response"})
+}
+
+func (s *MainSuite) TestRequestError(c *C) {
+	code, stdout, _ := s.run("", "down")
+	c.Check(code, Equals, exitRequest)
+	c.Check(stdout, Matches, "(?s)decision: +unknown\n.*status code: +503\n.*")
+
+	code, stdout, _ = s.run("", "-json", "down")
+	c.Check(code, Equals, exitRequest)
+	var out output
+	c.Assert(json.Unmarshal([]byte(stdout), &out), IsNil)
+	c.Check(out.Decision, Equals, "unknown")
+	c.Check(out.Reason, Equals, "transport")
+}
+
+func (s *MainSuite) TestSecretAndToken(c *C) {
+	path := filepath.Join(c.MkDir(), "secret")
+	c.Assert(os.WriteFile(path, []byte("file secret\n"), 0600), IsNil)
+	code, _, _ := s.run("human\n", "-secret-file", path, "-")
+	c.Check(code, Equals, exitPassed)
+	code, _, _ = s.run("", "-secret", "flag secret", "-secret-file", path, "human")
+	c.Check(code, Equals, exitPassed)
+	requests := s.server.Requests()
+	c.Assert(requests, HasLen, 2)
+	c.Check(requests[0].Secret, Equals, "file secret")
+	c.Check(requests[0].Response, Equals, "human")
+	c.Check(requests[1].Secret, Equals, "flag secret")
+}
+
+func (s *MainSuite) TestUsage(c *C) {
+	code, _, stderr := s.run("")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Matches, ".*a token is required\n")
+
+	delete(s.env, SecretEnv)
+	code, _, stderr = s.run("", "human")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stderr, Matches, ".*secret cannot be blank.*\n")
+
+	code, _, _ = s.run("", "-version", "v4", "human")
+	c.Check(code, Equals, exitUsage)
+	code, _, _ = s.run("", "-unknown")
+	c.Check(code, Equals, exitUsage)
+
+	s.env[SecretEnv] = "env secret"
+	code, stdout, stderr := s.run("", "human", "-json")
+	c.Check(code, Equals, exitUsage)
+	c.Check(stdout, Equals, "")
+	c.Check(stderr, Matches, `.*unexpected arguments \["-json"\], flags must precede the token\n`)
+	code, _, _ = s.run("", "-token", "human", "bot")
+	c.Check(code, Equals, exitUsage)
+	c.Check(s.server.Requests(), HasLen, 0)
+}
@@ -0,0 +1,136 @@
+package recaptcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const enterpriseLink = "https://recaptchaenterprise.googleapis.com/v1"
+
+type enterpriseEvent struct {
+	Token          string `json:"token"`
+	SiteKey        string `json:"siteKey"`
+	ExpectedAction string `json:"expectedAction,omitempty"`
+	UserIPAddress  string `json:"userIpAddress,omitempty"`
+	UserAgent      string `json:"userAgent,omitempty"`
+}
+
+type enterpriseRequest struct {
+	Event enterpriseEvent `json:"event"`
+}
+
+type enterpriseResponse struct {
+	Name         string `json:"name"`
+	RiskAnalysis struct {
+		Score   float32  `json:"score"`
+		Reasons []string `json:"reasons,omitempty"`
+	} `json:"riskAnalysis"`
+	TokenProperties struct {
+		Valid              bool      `json:"valid"`
+		InvalidReason      string    `json:"invalidReason,omitempty"`
+		Hostname           string    `json:"hostname,omitempty"`
+		AndroidPackageName string    `json:"androidPackageName,omitempty"`
+		Action             string    `json:"action,omitempty"`
+		CreateTime         time.Time `json:"createTime"`
+	} `json:"tokenProperties"`
+}
+
+// Enterprise reCAPTCHA Enterprise verifier creating assessments, see https://cloud.google.com/recaptcha-enterprise/docs/create-assessment
+type Enterprise struct {
+	client    netClient
+	ProjectID string
+	APIKey    string
+	// SiteKey is the key the challenge responses were issued for, it is sent with every assessment.
+	SiteKey        string
+	EnterpriseLink string
+	Timeout        time.Duration
+	horloge        clock
+}
+
+// NewEnterprise new Enterprise instance creating assessments in the given Google Cloud project,
+// the api key is created in the credentials page of the project and must allow the reCAPTCHA Enterprise API
+func NewEnterprise(projectID string, apiKey string, siteKey string, timeout time.Duration) (Enterprise, error) {
+	if projectID == "" || apiKey == "" || siteKey == "" {
+		return Enterprise{}, fmt.Errorf("recaptcha enterprise project, api key and site key cannot be blank")
+	}
+	return Enterprise{
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		horloge:        &realClock{},
+		ProjectID:      projectID,
+		APIKey:         apiKey,
+		SiteKey:        siteKey,
+		EnterpriseLink: enterpriseLink,
+		Timeout:        timeout,
+	}, nil
+}
+
+// Verify returns `nil` if no error and the client solved the challenge correctly
+func (e *Enterprise) Verify(challengeResponse string) error {
+	return e.VerifyContext(context.Background(), challengeResponse)
+}
+
+// VerifyContext is like Verify but the request to reCAPTCHA Enterprise is bound to ctx
+func (e *Enterprise) VerifyContext(ctx context.Context, challengeResponse string) error {
+	_, err := e.CheckContext(ctx, challengeResponse, VerifyOption{})
+	return err
+}
+
+// VerifyWithOptions returns `nil` if no error and the client solved the challenge correctly and all options are matching,
+// the options behave as for recaptcha V3. `Action`, `RemoteIP` and `UserAgent` are also sent with the assessment
+func (e *Enterprise) VerifyWithOptions(challengeResponse string, options VerifyOption) error {
+	return e.VerifyWithOptionsContext(context.Background(), challengeResponse, options)
+}
+
+// VerifyWithOptionsContext is like VerifyWithOptions but the request to reCAPTCHA Enterprise is bound to ctx
+func (e *Enterprise) VerifyWithOptionsContext(ctx context.Context, challengeResponse string, options VerifyOption) error {
+	_, err := e.CheckContext(ctx, challengeResponse, options)
+	return err
+}
+
+// Check is like VerifyWithOptions but also returns the assessment,
+// invalid tokens are reported as a remote error code holding the invalid reason
+func (e *Enterprise) Check(challengeResponse string, options VerifyOption) (VerifyResult, error) {
+	return e.CheckContext(context.Background(), challengeResponse, options)
+}
+
+// CheckContext is like Check but the request to reCAPTCHA Enterprise is bound to ctx
+func (e *Enterprise) CheckContext(ctx context.Context, challengeResponse string, options VerifyOption) (Result VerifyResult, Err error) {
+	body := enterpriseRequest{Event: enterpriseEvent{
+		Token:          challengeResponse,
+		SiteKey:        e.SiteKey,
+		ExpectedAction: options.Action,
+		UserIPAddress:  options.RemoteIP,
+		UserAgent:      options.UserAgent,
+	}}
+	var result enterpriseResponse
+	Err = postJSON(ctx, e.client, "recaptcha enterprise", e.endpoint("/projects/"+url.PathEscape(e.ProjectID)+"/assessments"), body, &result)
+	if Err != nil {
+		return
+	}
+	Result = VerifyResult{
+		Success:        result.TokenProperties.Valid,
+		ChallengeTS:    result.TokenProperties.CreateTime,
+		Hostname:       result.TokenProperties.Hostname,
+		ApkPackageName: result.TokenProperties.AndroidPackageName,
+		Action:         result.TokenProperties.Action,
+		Score:          result.RiskAnalysis.Score,
+		AssessmentName: result.Name,
+		Reasons:        result.RiskAnalysis.Reasons,
+	}
+	if !result.TokenProperties.Valid && result.TokenProperties.InvalidReason != "" {
+		Result.ErrorCodes = []string{result.TokenProperties.InvalidReason}
+	}
+	options.CData = ""
+	Err = checkResponse(&Result, options, e.horloge, true)
+	return
+}
+
+// endpoint returns the url of the given api path authenticated with the api key
+func (e *Enterprise) endpoint(path string) string {
+	return e.EnterpriseLink + path + "?" + url.Values{"key": {e.APIKey}}.Encode()
+}
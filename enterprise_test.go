@@ -0,0 +1,121 @@
+package recaptcha
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type EnterpriseSuite struct{}
+
+var _ = Suite(&EnterpriseSuite{})
+
+type mockStatusClient struct {
+	request *http.Request
+	status  int
+	body    string
+}
+
+func (m *mockStatusClient) Do(req *http.Request) (resp *http.Response, err error) {
+	m.request = req
+	resp = &http.Response{
+		Status:     http.StatusText(m.status),
+		StatusCode: m.status,
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(m.body))
+	return
+}
+
+const assessment = `
+{
+	"name": "projects/my-project/assessments/b0bd9e6a",
+	"riskAnalysis": {"score": 0.3, "reasons": ["AUTOMATION"]},
+	"tokenProperties": {
+		"valid": true,
+		"hostname": "test.com",
+		"action": "login",
+		"createTime": "2018-03-06T03:41:29.894Z"
+	}
+}`
+
+func (s *EnterpriseSuite) TestNewEnterprise(c *C) {
+	captcha, err := NewEnterprise("my-project", "my api key", "my site key", 10*time.Second)
+	c.Assert(err, IsNil)
+	c.Check(captcha.ProjectID, Equals, "my-project")
+	c.Check(captcha.APIKey, Equals, "my api key")
+	c.Check(captcha.SiteKey, Equals, "my site key")
+	c.Check(captcha.EnterpriseLink, Equals, enterpriseLink)
+
+	_, err = NewEnterprise("my-project", "", "my site key", 10*time.Second)
+	c.Check(err, NotNil)
+}
+
+func (s *EnterpriseSuite) TestCheck(c *C) {
+	client := &mockStatusClient{status: 200, body: assessment}
+	var captcha Verifier = &Enterprise{
+		client:         client,
+		ProjectID:      "my-project",
+		APIKey:         "my api key",
+		SiteKey:        "my site key",
+		EnterpriseLink: enterpriseLink,
+		horloge:        &mockClockWithinRespenseTime{},
+	}
+
+	result, err := captcha.Check("mycode", VerifyOption{
+		Action:       "login",
+		Hostname:     "test.com",
+		ResponseTime: 5 * time.Second,
+		Threshold:    0.2,
+		RemoteIP:     "123.123.123.123",
+		UserAgent:    "gopher/1.0",
+	})
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, true)
+	c.Check(result.Score, Equals, float32(0.3))
+	c.Check(result.Reasons, DeepEquals, []string{"AUTOMATION"})
+	c.Check(result.AssessmentName, Equals, "projects/my-project/assessments/b0bd9e6a")
+	c.Check(result.ChallengeTS.Equal(time.Date(2018, 3, 6, 3, 41, 29, 894000000, time.UTC)), Equals, true)
+
+	c.Check(client.request.URL.String(), Equals, enterpriseLink+"/projects/my-project/assessments?key=my+api+key")
+	c.Check(client.request.Header.Get("Content-Type"), Equals, "application/json")
+	var body map[string]map[string]string
+	c.Assert(json.NewDecoder(client.request.Body).Decode(&body), IsNil)
+	c.Check(body["event"], DeepEquals, map[string]string{
+		"token":          "mycode",
+		"siteKey":        "my site key",
+		"expectedAction": "login",
+		"userIpAddress":  "123.123.123.123",
+		"userAgent":      "gopher/1.0",
+	})
+
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{Action: "signup", Threshold: 0.1})
+	c.Check(errors.Is(err, ErrActionMismatch), Equals, true)
+
+	client.body = `{"name": "projects/my-project/assessments/c1", "tokenProperties": {"valid": false, "invalidReason": "DUPE"}}`
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+	c.Check(result.ErrorCodes, DeepEquals, []string{"DUPE"})
+	c.Check(result.AssessmentName, Equals, "projects/my-project/assessments/c1")
+}
+
+func (s *EnterpriseSuite) TestAPIError(c *C) {
+	client := &mockStatusClient{status: 403, body: `{"error": {"code": 403, "message": "API key not valid.", "status": "PERMISSION_DENIED"}}`}
+	captcha := Enterprise{client: client}
+
+	err := captcha.Verify("mycode")
+	c.Assert(err, NotNil)
+	c.Check(err.(*Error).RequestError, Equals, true)
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+	c.Check(err, ErrorMatches, "recaptcha enterprise endpoint answered with status 403: 'API key not valid.'")
+
+	client.status, client.body = 200, `not json`
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrDecode), Equals, true)
+}
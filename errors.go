@@ -0,0 +1,129 @@
+package recaptcha
+
+import "errors"
+
+// Reason why a verification failed
+type Reason int8
+
+const (
+	// ReasonNone no failure
+	ReasonNone Reason = iota
+	// ReasonTransport the request to recaptcha could not be sent or its response could not be read
+	ReasonTransport
+	// ReasonDecode the recaptcha response body is not valid json
+	ReasonDecode
+	// ReasonRemoteErrorCodes recaptcha answered with error codes, see Error.ErrorCodes
+	ReasonRemoteErrorCodes
+	// ReasonInvalidSolution the challenge was not solved or the remote IP did not match
+	ReasonInvalidSolution
+	// ReasonHostnameMismatch the response hostname is not the expected one
+	ReasonHostnameMismatch
+	// ReasonApkPackageNameMismatch the response apk package name is not the expected one
+	ReasonApkPackageNameMismatch
+	// ReasonActionMismatch the response action is not the expected one
+	ReasonActionMismatch
+	// ReasonScoreBelowThreshold the response score is lower than the threshold
+	ReasonScoreBelowThreshold
+	// ReasonResponseTooSlow the challenge took longer than the allowed response time to solve
+	ReasonResponseTooSlow
+	// ReasonCDataMismatch the response customer data is not the expected one
+	ReasonCDataMismatch
+	// ReasonReplayedToken the challenge response was already verified, detected by the ReplayStore
+	ReasonReplayedToken
+	// ReasonCircuitOpen recaptcha was not called because the CircuitBreaker is open
+	ReasonCircuitOpen
+	// ReasonChallengeRequired the score falls in a band of the action policy asking for a challenge
+	ReasonChallengeRequired
+	// ReasonReplayStoreFull the ReplayStore is full of unexpired keys and cannot guard the challenge response
+	ReasonReplayStoreFull
+	// ReasonInvalidArgument the call was made with an invalid argument, nothing was sent
+	ReasonInvalidArgument
+)
+
+var reasonNames = map[Reason]string{
+	ReasonNone:                   "none",
+	ReasonTransport:              "transport",
+	ReasonDecode:                 "decode",
+	ReasonRemoteErrorCodes:       "remote_error_codes",
+	ReasonInvalidSolution:        "invalid_solution",
+	ReasonHostnameMismatch:       "hostname_mismatch",
+	ReasonApkPackageNameMismatch: "apk_package_name_mismatch",
+	ReasonActionMismatch:         "action_mismatch",
+	ReasonScoreBelowThreshold:    "score_below_threshold",
+	ReasonResponseTooSlow:        "response_too_slow",
+	ReasonCDataMismatch:          "cdata_mismatch",
+	ReasonReplayedToken:          "replayed_token",
+	ReasonCircuitOpen:            "circuit_open",
+	ReasonChallengeRequired:      "challenge_required",
+	ReasonReplayStoreFull:        "replay_store_full",
+	ReasonInvalidArgument:        "invalid_argument",
+}
+
+func (r Reason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Sentinel errors matching an *Error of the corresponding Reason with errors.Is
+var (
+	ErrTransport              = errors.New("recaptcha: transport failure")
+	ErrDecode                 = errors.New("recaptcha: invalid response body")
+	ErrRemoteErrorCodes       = errors.New("recaptcha: remote error codes")
+	ErrInvalidSolution        = errors.New("recaptcha: invalid challenge solution")
+	ErrHostnameMismatch       = errors.New("recaptcha: hostname mismatch")
+	ErrApkPackageNameMismatch = errors.New("recaptcha: apk package name mismatch")
+	ErrActionMismatch         = errors.New("recaptcha: action mismatch")
+	ErrScoreBelowThreshold    = errors.New("recaptcha: score below threshold")
+	ErrResponseTooSlow        = errors.New("recaptcha: response too slow")
+	ErrCDataMismatch          = errors.New("recaptcha: cdata mismatch")
+	ErrReplayedToken          = errors.New("recaptcha: replayed token")
+	ErrCircuitOpen            = errors.New("recaptcha: circuit breaker open")
+	ErrChallengeRequired      = errors.New("recaptcha: challenge required")
+	ErrReplayStoreFull        = errors.New("recaptcha: replay store full")
+	ErrInvalidArgument        = errors.New("recaptcha: invalid argument")
+)
+
+var reasonErrors = map[Reason]error{
+	ReasonTransport:              ErrTransport,
+	ReasonDecode:                 ErrDecode,
+	ReasonRemoteErrorCodes:       ErrRemoteErrorCodes,
+	ReasonInvalidSolution:        ErrInvalidSolution,
+	ReasonHostnameMismatch:       ErrHostnameMismatch,
+	ReasonApkPackageNameMismatch: ErrApkPackageNameMismatch,
+	ReasonActionMismatch:         ErrActionMismatch,
+	ReasonScoreBelowThreshold:    ErrScoreBelowThreshold,
+	ReasonResponseTooSlow:        ErrResponseTooSlow,
+	ReasonCDataMismatch:          ErrCDataMismatch,
+	ReasonReplayedToken:          ErrReplayedToken,
+	ReasonCircuitOpen:            ErrCircuitOpen,
+	ReasonChallengeRequired:      ErrChallengeRequired,
+	ReasonReplayStoreFull:        ErrReplayStoreFull,
+	ReasonInvalidArgument:        ErrInvalidArgument,
+}
+
+// Error custom error to pass ErrorCodes and RequestError to user.
+type Error struct {
+	msg string
+	err error
+	// ErrorCodes contains any error codes from the recaptcha response.
+	ErrorCodes []string
+	// RequestError is true if the verify request to recaptcha failed.
+	RequestError bool
+	// Reason tells why the verification failed.
+	Reason Reason
+	// StatusCode is the http status answered by the verification service when it was not a success.
+	StatusCode int
+}
+
+func (e *Error) Error() string { return e.msg }
+
+// Unwrap returns the underlying transport or json error, if any
+func (e *Error) Unwrap() error { return e.err }
+
+// Is reports whether target is the sentinel error of e.Reason
+func (e *Error) Is(target error) bool {
+	sentinel, ok := reasonErrors[e.Reason]
+	return ok && sentinel == target
+}
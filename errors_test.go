@@ -0,0 +1,75 @@
+package recaptcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ErrorsSuite struct{}
+
+var _ = Suite(&ErrorsSuite{})
+
+func (s *ErrorsSuite) TestReasons(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockV3FullResponseClient{},
+		horloge: &mockClockOverRespenseTime{},
+		Version: V3,
+	}
+
+	cases := []struct {
+		options  VerifyOption
+		reason   Reason
+		sentinel error
+	}{
+		{VerifyOption{Hostname: "test.com"}, ReasonHostnameMismatch, ErrHostnameMismatch},
+		{VerifyOption{ApkPackageName: "com.test.app"}, ReasonApkPackageNameMismatch, ErrApkPackageNameMismatch},
+		{VerifyOption{ResponseTime: 5 * time.Second, Threshold: 0.1}, ReasonResponseTooSlow, ErrResponseTooSlow},
+		{VerifyOption{Action: "login", Threshold: 0.1}, ReasonActionMismatch, ErrActionMismatch},
+		{VerifyOption{Threshold: 0.6}, ReasonScoreBelowThreshold, ErrScoreBelowThreshold},
+	}
+	for _, t := range cases {
+		err := captcha.VerifyWithOptions("mycode", t.options)
+		c.Assert(err, NotNil)
+		var recaptchaErr *Error
+		c.Assert(errors.As(err, &recaptchaErr), Equals, true)
+		c.Check(recaptchaErr.Reason, Equals, t.reason)
+		c.Check(errors.Is(err, t.sentinel), Equals, true)
+		c.Check(errors.Is(err, ErrTransport), Equals, false)
+		c.Check(errors.Unwrap(err), IsNil)
+	}
+
+	captcha.client = &mockFailedClientNoOptions{}
+	err := captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+
+	captcha.client = &mockInvalidSolutionClient{}
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrInvalidSolution), Equals, true)
+}
+
+func (s *ErrorsSuite) TestUnwrap(c *C) {
+	captcha := ReCAPTCHA{
+		client: &mockInvalidClient{},
+	}
+	err := captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrDecode), Equals, true)
+	var syntaxErr *json.SyntaxError
+	c.Check(errors.As(err, &syntaxErr), Equals, true)
+
+	captcha.client = &mockContextClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = captcha.VerifyContext(ctx, "mycode")
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+	c.Check(errors.Is(err, context.Canceled), Equals, true)
+}
+
+func (s *ErrorsSuite) TestReasonString(c *C) {
+	c.Check(ReasonNone.String(), Equals, "none")
+	c.Check(ReasonScoreBelowThreshold.String(), Equals, "score_below_threshold")
+	c.Check(Reason(100).String(), Equals, "unknown")
+}
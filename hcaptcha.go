@@ -0,0 +1,104 @@
+package recaptcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const hCaptchaLink = "https://api.hcaptcha.com/siteverify"
+
+type hCaptchaResponse struct {
+	Success     bool      `json:"success"`
+	ChallengeTS time.Time `json:"challenge_ts"`
+	Hostname    string    `json:"hostname,omitempty"`
+	Score       *float32  `json:"score,omitempty"`
+	ErrorCodes  []string  `json:"error-codes,omitempty"`
+}
+
+// HCaptcha hCaptcha verifier, see https://docs.hcaptcha.com/#verify-the-user-response-server-side
+type HCaptcha struct {
+	client netClient
+	Secret string
+	// SiteKey is optional, when set hCaptcha also checks the challenge response was issued for this site key.
+	SiteKey      string
+	HCaptchaLink string
+	Timeout      time.Duration
+	horloge      clock
+}
+
+// NewHCaptcha new HCaptcha instance, get your secret from https://dashboard.hcaptcha.com/settings
+func NewHCaptcha(hCaptchaSecret string, timeout time.Duration) (HCaptcha, error) {
+	if hCaptchaSecret == "" {
+		return HCaptcha{}, fmt.Errorf("hcaptcha secret cannot be blank")
+	}
+	return HCaptcha{
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		horloge:      &realClock{},
+		Secret:       hCaptchaSecret,
+		HCaptchaLink: hCaptchaLink,
+		Timeout:      timeout,
+	}, nil
+}
+
+// Verify returns `nil` if no error and the client solved the challenge correctly
+func (h *HCaptcha) Verify(challengeResponse string) error {
+	return h.VerifyContext(context.Background(), challengeResponse)
+}
+
+// VerifyContext is like Verify but the request to hCaptcha is bound to ctx
+func (h *HCaptcha) VerifyContext(ctx context.Context, challengeResponse string) error {
+	_, err := h.CheckContext(ctx, challengeResponse, VerifyOption{})
+	return err
+}
+
+// VerifyWithOptions returns `nil` if no error and the client solved the challenge correctly and all options are matching.
+// `Action`, `ApkPackageName` and `CData` are ignored, `Threshold` is only checked when hCaptcha returns a score (Enterprise)
+func (h *HCaptcha) VerifyWithOptions(challengeResponse string, options VerifyOption) error {
+	return h.VerifyWithOptionsContext(context.Background(), challengeResponse, options)
+}
+
+// VerifyWithOptionsContext is like VerifyWithOptions but the request to hCaptcha is bound to ctx
+func (h *HCaptcha) VerifyWithOptionsContext(ctx context.Context, challengeResponse string, options VerifyOption) error {
+	_, err := h.CheckContext(ctx, challengeResponse, options)
+	return err
+}
+
+// Check is like VerifyWithOptions but also returns the decoded hCaptcha response.
+// hCaptcha Enterprise scores are risk scores, 1.0 being the most likely bot, VerifyResult.Score holds 1 - score
+// so it has the same meaning as recaptcha V3 scores and `Threshold`
+func (h *HCaptcha) Check(challengeResponse string, options VerifyOption) (VerifyResult, error) {
+	return h.CheckContext(context.Background(), challengeResponse, options)
+}
+
+// CheckContext is like Check but the request to hCaptcha is bound to ctx
+func (h *HCaptcha) CheckContext(ctx context.Context, challengeResponse string, options VerifyOption) (Result VerifyResult, Err error) {
+	formValues := url.Values{"secret": {h.Secret}, "response": {challengeResponse}}
+	if options.RemoteIP != "" {
+		formValues.Set("remoteip", options.RemoteIP)
+	}
+	if h.SiteKey != "" {
+		formValues.Set("sitekey", h.SiteKey)
+	}
+	var result hCaptchaResponse
+	Err = postForm(ctx, h.client, "hcaptcha", h.HCaptchaLink, formValues, &result)
+	if Err != nil {
+		return
+	}
+	Result = VerifyResult{
+		Success:     result.Success,
+		ChallengeTS: result.ChallengeTS,
+		Hostname:    result.Hostname,
+		ErrorCodes:  result.ErrorCodes,
+	}
+	if result.Score != nil {
+		Result.Score = 1 - *result.Score
+	}
+	options.Action, options.ApkPackageName, options.CData = "", "", ""
+	Err = checkResponse(&Result, options, h.horloge, result.Score != nil)
+	return
+}
@@ -0,0 +1,81 @@
+package recaptcha
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type HCaptchaSuite struct{}
+
+var _ = Suite(&HCaptchaSuite{})
+
+type mockBodyClient struct {
+	request *http.Request
+	body    string
+}
+
+func (m *mockBodyClient) Do(req *http.Request) (resp *http.Response, err error) {
+	m.request = req
+	resp = &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(m.body))
+	return
+}
+
+func (s *HCaptchaSuite) TestNewHCaptcha(c *C) {
+	captcha, err := NewHCaptcha("my secret", 10*time.Second)
+	c.Assert(err, IsNil)
+	c.Check(captcha.Secret, Equals, "my secret")
+	c.Check(captcha.HCaptchaLink, Equals, hCaptchaLink)
+	c.Check(captcha.Timeout, Equals, 10*time.Second)
+
+	_, err = NewHCaptcha("", 10*time.Second)
+	c.Check(err, NotNil)
+}
+
+func (s *HCaptchaSuite) TestVerify(c *C) {
+	client := &mockBodyClient{body: `{"success": true, "challenge_ts": "2018-03-06T03:41:29Z", "hostname": "test.com"}`}
+	var captcha Verifier = &HCaptcha{
+		client:       client,
+		Secret:       "my secret",
+		SiteKey:      "my sitekey",
+		HCaptchaLink: hCaptchaLink,
+	}
+
+	err := captcha.VerifyWithOptions("mycode", VerifyOption{Hostname: "test.com", RemoteIP: "123.123.123.123", Action: "ignored", ApkPackageName: "ignored", Threshold: 0.9})
+	c.Assert(err, IsNil)
+	c.Check(client.request.URL.String(), Equals, hCaptchaLink)
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("secret"), Equals, "my secret")
+	c.Check(client.request.PostForm.Get("response"), Equals, "mycode")
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "123.123.123.123")
+	c.Check(client.request.PostForm.Get("sitekey"), Equals, "my sitekey")
+
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{Hostname: "test2.com"})
+	c.Check(errors.Is(err, ErrHostnameMismatch), Equals, true)
+
+	client.body = `{"success": false, "error-codes": ["invalid-input-response"]}`
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+	c.Check(err.(*Error).ErrorCodes, DeepEquals, []string{"invalid-input-response"})
+}
+
+func (s *HCaptchaSuite) TestEnterpriseScore(c *C) {
+	client := &mockBodyClient{body: `{"success": true, "challenge_ts": "2018-03-06T03:41:29Z", "score": 0.2}`}
+	captcha := HCaptcha{client: client}
+
+	result, err := captcha.Check("mycode", VerifyOption{Threshold: 0.7})
+	c.Assert(err, IsNil)
+	c.Check(result.Score, Equals, float32(0.8))
+
+	client.body = `{"success": true, "challenge_ts": "2018-03-06T03:41:29Z", "score": 0.9}`
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+}
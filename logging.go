@@ -0,0 +1,71 @@
+package recaptcha
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// tokenHashLength number of hex characters of the token hash logged, enough to correlate records
+const tokenHashLength = 12
+
+// logVerification emits the record of a verification, the challenge response is only logged as a truncated hash
+func (r *ReCAPTCHA) logVerification(ctx context.Context, challengeResponse string, result VerifyResult, err error, latency time.Duration) {
+	level := slog.LevelInfo
+	if result.FailedOpen {
+		level = slog.LevelWarn
+	} else if recaptchaErr, ok := err.(*Error); ok && recaptchaErr.RequestError {
+		level = slog.LevelError
+	}
+	if !r.Logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("token_hash", hashToken(challengeResponse)[:tokenHashLength]),
+		slog.String("version", r.Version.String()),
+		slog.String("action", result.Action),
+		slog.String("hostname", result.Hostname),
+		slog.Float64("score", float64(result.Score)),
+		slog.String("decision", result.Decision.String()),
+		slog.String("reason", reasonOf(err).String()),
+		slog.Int("attempts", result.Attempts),
+		slog.Duration("latency", latency),
+	}
+	if len(result.ErrorCodes) > 0 {
+		attrs = append(attrs, slog.Any("error_codes", result.ErrorCodes))
+	}
+	if result.ShadowError != nil {
+		attrs = append(attrs, slog.String("shadow_reason", reasonOf(result.ShadowError).String()), slog.Any("shadow_error", result.ShadowError))
+	}
+	if result.FailedOpen {
+		attrs = append(attrs, slog.Bool("failed_open", true), slog.Any("error", result.OutageError))
+	} else if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	r.Logger.LogAttrs(ctx, level, "recaptcha verification", attrs...)
+}
+
+// LogValue logs the error as a group of its message, reason, status code and error codes
+func (e *Error) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("msg", e.msg),
+		slog.String("reason", e.Reason.String()),
+	}
+	if e.StatusCode != 0 {
+		attrs = append(attrs, slog.Int("status", e.StatusCode))
+	}
+	if len(e.ErrorCodes) > 0 {
+		attrs = append(attrs, slog.Any("error_codes", e.ErrorCodes))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue logs the configuration without the secret
+func (r ReCAPTCHA) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("version", r.Version.String()),
+		slog.String("link", r.ReCAPTCHALink),
+		slog.Duration("timeout", r.Timeout),
+		slog.String("secret", "REDACTED"),
+	)
+}
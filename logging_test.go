@@ -0,0 +1,64 @@
+package recaptcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type LoggingSuite struct{}
+
+var _ = Suite(&LoggingSuite{})
+
+func (s *LoggingSuite) TestLogger(c *C) {
+	var buf bytes.Buffer
+	captcha := ReCAPTCHA{
+		client:  &mockBodyClient{body: v3Body("0.3")},
+		Secret:  "my secret",
+		Version: V3,
+		Logger:  slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	_, err := captcha.Check("my token", VerifyOption{Threshold: 0.5})
+	c.Check(err, NotNil)
+
+	var record map[string]interface{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &record), IsNil)
+	c.Check(record["level"], Equals, "INFO")
+	c.Check(record["msg"], Equals, "recaptcha verification")
+	c.Check(record["token_hash"], Equals, hashToken("my token")[:12])
+	c.Check(record["version"], Equals, "v3")
+	c.Check(record["action"], Equals, "comment")
+	c.Check(record["decision"], Equals, "deny")
+	c.Check(record["reason"], Equals, "score_below_threshold")
+	c.Check(record["attempts"], Equals, float64(1))
+	c.Check(record["error"].(map[string]interface{})["reason"], Equals, "score_below_threshold")
+	c.Check(strings.Contains(buf.String(), "my token"), Equals, false)
+	c.Check(strings.Contains(buf.String(), "my secret"), Equals, false)
+}
+
+func (s *LoggingSuite) TestLoggerFailedOpen(c *C) {
+	var buf bytes.Buffer
+	captcha := ReCAPTCHA{
+		client: &mockSequenceClient{statuses: []int{503}},
+		Outage: &OutagePolicy{Mode: FailOpen},
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	_, err := captcha.Check("my token", VerifyOption{})
+	c.Check(err, IsNil)
+	var record map[string]interface{}
+	c.Assert(json.Unmarshal(buf.Bytes(), &record), IsNil)
+	c.Check(record["level"], Equals, "WARN")
+	c.Check(record["failed_open"], Equals, true)
+	c.Check(record["error"].(map[string]interface{})["status"], Equals, float64(503))
+}
+
+func (s *LoggingSuite) TestLogValue(c *C) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("config", "captcha", ReCAPTCHA{Secret: "my secret", Version: V2})
+	c.Check(strings.Contains(buf.String(), "my secret"), Equals, false)
+	c.Check(strings.Contains(buf.String(), "captcha.secret=REDACTED"), Equals, true)
+}
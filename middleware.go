@@ -0,0 +1,121 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// RejectHandler writes the response sent when a request fails verification, err is the verification error
+type RejectHandler func(w http.ResponseWriter, req *http.Request, err error)
+
+// DefaultRejectHandler replies with a 403 Forbidden
+func DefaultRejectHandler(w http.ResponseWriter, req *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+type middleware struct {
+	captcha   *ReCAPTCHA
+	source    TokenSource
+	options   VerifyOption
+	reject    RejectHandler
+	challenge http.Handler
+	pass      *HumanPass
+}
+
+// MiddlewareOption customizes the handler returned by Middleware
+type MiddlewareOption func(*middleware)
+
+// WithTokenSource sets where the challenge response is read from, defaults to ReCAPTCHA.TokenSource
+func WithTokenSource(source TokenSource) MiddlewareOption {
+	return func(m *middleware) {
+		m.source = source
+	}
+}
+
+// WithVerifyOption sets the options the challenge is verified with
+func WithVerifyOption(options VerifyOption) MiddlewareOption {
+	return func(m *middleware) {
+		m.options = options
+	}
+}
+
+// WithRejectHandler sets the handler called when verification fails, defaults to DefaultRejectHandler
+func WithRejectHandler(reject RejectHandler) MiddlewareOption {
+	return func(m *middleware) {
+		m.reject = reject
+	}
+}
+
+// WithChallengeHandler sets the handler called instead of the RejectHandler when the score band of the action policy
+// asks for a challenge, the VerifyResult is available to it through ResultFromContext
+func WithChallengeHandler(challenge http.Handler) MiddlewareOption {
+	return func(m *middleware) {
+		m.challenge = challenge
+	}
+}
+
+// WithHumanPass issues a pass cookie after successful verifications and lets requests carrying a valid pass
+// for the action of the VerifyOption through without a challenge response, VerifyResult.FromPass being set
+func WithHumanPass(pass *HumanPass) MiddlewareOption {
+	return func(m *middleware) {
+		m.pass = pass
+	}
+}
+
+// Middleware returns a net/http middleware that only lets through requests carrying a challenge response
+// passing verification, the VerifyResult is available to the wrapped handler through ResultFromContext.
+// Like CheckRequest the remote IP is taken from the request unless set in the VerifyOption
+func (r *ReCAPTCHA) Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := newMiddleware(r, opts)
+	return m.wrap
+}
+
+func newMiddleware(r *ReCAPTCHA, opts []MiddlewareOption) *middleware {
+	m := &middleware{
+		captcha: r,
+		reject:  DefaultRejectHandler,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *middleware) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if m.pass != nil {
+			if pass, ok := m.pass.Check(req, m.options.Action); ok && pass.Score >= m.captcha.passThreshold(m.options) {
+				result := VerifyResult{Success: true, Score: pass.Score, Action: pass.Action, Decision: DecisionAllow, FromPass: true}
+				next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), result)))
+				return
+			}
+		}
+		result, err := m.captcha.checkRequest(req, m.source, m.options)
+		if err != nil {
+			if m.challenge != nil && errors.Is(err, ErrChallengeRequired) {
+				m.challenge.ServeHTTP(w, req.WithContext(NewContext(req.Context(), result)))
+				return
+			}
+			m.reject(w, req, err)
+			return
+		}
+		if m.pass != nil {
+			m.pass.Issue(w, req, result)
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), result)))
+	})
+}
+
+type resultKey struct{}
+
+// NewContext returns a copy of ctx carrying result
+func NewContext(ctx context.Context, result VerifyResult) context.Context {
+	return context.WithValue(ctx, resultKey{}, result)
+}
+
+// ResultFromContext returns the VerifyResult stored in ctx by the middleware, if any
+func ResultFromContext(ctx context.Context) (VerifyResult, bool) {
+	result, ok := ctx.Value(resultKey{}).(VerifyResult)
+	return result, ok
+}
@@ -0,0 +1,111 @@
+package recaptcha
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type MiddlewareSuite struct{}
+
+var _ = Suite(&MiddlewareSuite{})
+
+func formRequest(values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func (s *MiddlewareSuite) TestMiddleware(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockV3FullResponseClient{},
+		Version: V3,
+	}
+	var got VerifyResult
+	handler := captcha.Middleware(WithVerifyOption(VerifyOption{Action: "homepage", Threshold: 0.2}))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var ok bool
+		got, ok = ResultFromContext(req.Context())
+		c.Check(ok, Equals, true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	c.Check(got.Action, Equals, "homepage")
+	c.Check(got.Score, Equals, float32(0.3))
+
+	handler = captcha.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Error("handler must not be called")
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+}
+
+func (s *MiddlewareSuite) TestMiddlewareOptions(c *C) {
+	captcha := ReCAPTCHA{
+		client: &mockFailedClientNoOptions{},
+	}
+	var rejected error
+	handler := captcha.Middleware(
+		WithTokenSource(FormToken("token")),
+		WithRejectHandler(func(w http.ResponseWriter, req *http.Request, err error) {
+			rejected = err
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	)(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{"token": {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusTeapot)
+	c.Check(errors.Is(rejected, ErrRemoteErrorCodes), Equals, true)
+
+	_, ok := ResultFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	c.Check(ok, Equals, false)
+}
+
+func (s *MiddlewareSuite) TestMiddlewareChallenge(c *C) {
+	client := &mockBodyClient{body: v3Body("0.3")}
+	captcha := ReCAPTCHA{
+		client:  client,
+		Version: V3,
+		Policies: PolicyTable{{Action: "comment", Bands: []ScoreBand{
+			{MinScore: 0.7, Decision: DecisionAllow},
+			{MinScore: 0.2, Decision: DecisionChallenge},
+		}}},
+	}
+	var challenged VerifyResult
+	protect := captcha.Middleware(WithChallengeHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		challenged, _ = ResultFromContext(req.Context())
+		w.WriteHeader(http.StatusUnauthorized)
+	})))
+	handler := protect(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusUnauthorized)
+	c.Check(challenged.Decision, Equals, DecisionChallenge)
+
+	client.body = v3Body("0.1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+
+	client.body = v3Body("0.8")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+
+	// without challenge handler challenges are rejected
+	client.body = v3Body("0.3")
+	rec = httptest.NewRecorder()
+	captcha.Middleware()(http.NotFoundHandler()).ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+}
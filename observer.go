@@ -0,0 +1,65 @@
+package recaptcha
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Observation describes a single verification, it is passed to the Observer of ReCAPTCHA
+type Observation struct {
+	Version  VERSION
+	Action   string
+	Hostname string
+	Score    float32
+	// Reason is ReasonNone when the verification succeeded.
+	Reason Reason
+	// ShadowReason is the reason the verification would have failed for without shadow mode.
+	ShadowReason Reason
+	Decision     Decision
+	// StatusCode is the http status of the last recaptcha answer, zero when none was received.
+	StatusCode int
+	// Latency is the time spent verifying, retries included.
+	Latency  time.Duration
+	Attempts int
+}
+
+// Observer is notified of every verification, for instance to feed metrics.
+// Observe is called synchronously so it must be fast and safe for concurrent use
+type Observer interface {
+	Observe(o Observation)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(o Observation)
+
+// Observe calls f(o)
+func (f ObserverFunc) Observe(o Observation) {
+	f(o)
+}
+
+// reasonOf returns the Reason of err, ReasonNone when err is nil or not an *Error
+func reasonOf(err error) Reason {
+	var recaptchaErr *Error
+	if errors.As(err, &recaptchaErr) {
+		return recaptchaErr.Reason
+	}
+	return ReasonNone
+}
+
+// instrumentedClient propagates the trace context to the requests sent through client
+// and remembers the status of the last response received
+type instrumentedClient struct {
+	client     netClient
+	tracer     Tracer
+	statusCode int
+}
+
+func (s *instrumentedClient) Do(req *http.Request) (*http.Response, error) {
+	s.tracer.Inject(req.Context(), req.Header)
+	resp, err := s.client.Do(req)
+	if err == nil {
+		s.statusCode = resp.StatusCode
+	}
+	return resp, err
+}
@@ -0,0 +1,100 @@
+package recaptcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ObserverSuite struct{}
+
+var _ = Suite(&ObserverSuite{})
+
+func (s *ObserverSuite) TestObserve(c *C) {
+	var observations []Observation
+	captcha := ReCAPTCHA{
+		client:   &mockSequenceClient{statuses: []int{503, 200}, body: v3Body("0.3")},
+		Version:  V3,
+		Retry:    &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		Observer: ObserverFunc(func(o Observation) { observations = append(observations, o) }),
+	}
+	_, err := captcha.Check("mycode", VerifyOption{Threshold: 0.5})
+	c.Check(err, ErrorMatches, "received score .*")
+	c.Assert(observations, HasLen, 1)
+	o := observations[0]
+	c.Check(o.Version, Equals, V3)
+	c.Check(o.Action, Equals, "comment")
+	c.Check(o.Score, Equals, float32(0.3))
+	c.Check(o.Reason, Equals, ReasonScoreBelowThreshold)
+	c.Check(o.Decision, Equals, DecisionDeny)
+	c.Check(o.StatusCode, Equals, 200)
+	c.Check(o.Attempts, Equals, 2)
+	c.Check(o.Latency > 0, Equals, true)
+
+	captcha.client = &mockSequenceClient{statuses: []int{0}}
+	captcha.Retry = nil
+	_, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(err, NotNil)
+	c.Assert(observations, HasLen, 2)
+	c.Check(observations[1].Reason, Equals, ReasonTransport)
+	c.Check(observations[1].StatusCode, Equals, 0)
+}
+
+func (s *ObserverSuite) TestPrometheusObserver(c *C) {
+	p := NewPrometheusObserver()
+	p.MaxActions = 1
+	p.Observe(Observation{Version: V3, Action: "login", Score: 0.9, Decision: DecisionAllow, StatusCode: 200, Latency: 80 * time.Millisecond, Attempts: 1})
+	p.Observe(Observation{Version: V3, Action: "login", Score: 0.1, Reason: ReasonScoreBelowThreshold, StatusCode: 200, Latency: 300 * time.Millisecond, Attempts: 1})
+	p.Observe(Observation{Version: V3, Action: "sig\"nup", Score: 0.7, StatusCode: 200, Latency: 80 * time.Millisecond, Attempts: 1})
+	p.Observe(Observation{Version: V2, Reason: ReasonTransport, Latency: 2 * time.Second, Attempts: 3})
+	p.ObserveBreaker(BreakerClosed, BreakerOpen)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	c.Check(rec.Header().Get("Content-Type"), Matches, "text/plain; version=0.0.4.*")
+	body := rec.Body.String()
+	for _, line := range []string{
+		`recaptcha_verifications_total{version="v2",action="",reason="transport"} 1`,
+		`recaptcha_verifications_total{version="v3",action="login",reason="none"} 1`,
+		`recaptcha_verifications_total{version="v3",action="login",reason="score_below_threshold"} 1`,
+		`recaptcha_verifications_total{version="v3",action="other",reason="none"} 1`,
+		`recaptcha_responses_total{status="200"} 3`,
+		`recaptcha_verification_duration_seconds_bucket{version="v3",le="0.1"} 2`,
+		`recaptcha_verification_duration_seconds_bucket{version="v3",le="+Inf"} 3`,
+		`recaptcha_verification_duration_seconds_count{version="v2"} 1`,
+		`recaptcha_score_bucket{action="login",le="0.1"} 1`,
+		`recaptcha_score_count{action="login"} 2`,
+		`recaptcha_breaker_state 1`,
+		`recaptcha_breaker_transitions_total{state="open"} 1`,
+	} {
+		c.Check(strings.Contains(body, line+"\n"), Equals, true, Commentf("missing %s in\n%s", line, body))
+	}
+
+	c.Check(escapeLabel("a\"b\\c\nd"), Equals, `a\"b\\c\nd`)
+}
+
+func (s *ObserverSuite) TestPrometheusObserverZeroValue(c *C) {
+	p := &PrometheusObserver{}
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	c.Check(strings.Contains(rec.Body.String(), "recaptcha_breaker_state 0\n"), Equals, true)
+
+	p.Observe(Observation{Version: V3, Action: "login", Score: 0.9, StatusCode: 200, Latency: 80 * time.Millisecond, Attempts: 1})
+	p.Observe(Observation{Version: V3, Action: "signup", Score: 0.9, StatusCode: 200, Latency: 80 * time.Millisecond, Attempts: 1})
+	p.ObserveBreaker(BreakerClosed, BreakerOpen)
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`recaptcha_verifications_total{version="v3",action="login",reason="none"} 1`,
+		`recaptcha_verifications_total{version="v3",action="signup",reason="none"} 1`,
+		`recaptcha_verification_duration_seconds_bucket{version="v3",le="0.1"} 2`,
+		`recaptcha_breaker_transitions_total{state="open"} 1`,
+	} {
+		c.Check(strings.Contains(body, line+"\n"), Equals, true, Commentf("missing %s in\n%s", line, body))
+	}
+}
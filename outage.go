@@ -0,0 +1,104 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// OutageMode what verification does when recaptcha cannot be reached
+type OutageMode int8
+
+const (
+	// FailClosed verification fails with the request error
+	FailClosed OutageMode = iota
+	// FailOpen verification passes unverified, VerifyResult.FailedOpen being set
+	FailOpen
+)
+
+// OutagePolicy decides whether requests pass when recaptcha cannot be reached, that is when the request
+// to recaptcha fails, times out, is answered with a 5xx status or the circuit breaker is open.
+// Garbage answers and requests canceled by the caller always fail closed.
+// The zero value fails closed, it must not be copied once used
+type OutagePolicy struct {
+	Mode OutageMode
+	// Budget caps the unverified passes per client IP within Window when failing open, unlimited when zero.
+	// Requests without RemoteIP cannot be accounted for so they fail closed when a budget is set.
+	Budget int
+	// Window is the period Budget applies to, a minute when zero.
+	Window time.Duration
+
+	mu        sync.Mutex
+	passes    map[string]*outageBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type outageBucket struct {
+	start time.Time
+	count int
+}
+
+// isOutage reports whether err tells recaptcha could not be reached, rather than answered garbage
+// or was abandoned by the caller
+func isOutage(err error) bool {
+	var recaptchaErr *Error
+	if !errors.As(err, &recaptchaErr) || !recaptchaErr.RequestError {
+		return false
+	}
+	switch recaptchaErr.Reason {
+	case ReasonCircuitOpen:
+		return true
+	case ReasonTransport:
+		if recaptchaErr.StatusCode != 0 {
+			return recaptchaErr.StatusCode >= 500
+		}
+		return !errors.Is(err, context.Canceled)
+	}
+	return false
+}
+
+// allow reports whether a request from ip may pass unverified, spending the budget if any
+func (p *OutagePolicy) allow(ip string) bool {
+	if p == nil || p.Mode != FailOpen {
+		return false
+	}
+	if p.Budget <= 0 {
+		return true
+	}
+	if ip == "" {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.now != nil {
+		now = p.now()
+	}
+	window := p.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	if p.passes == nil {
+		p.passes = make(map[string]*outageBucket)
+	}
+	if now.Sub(p.lastSweep) >= window {
+		for key, bucket := range p.passes {
+			if now.Sub(bucket.start) >= window {
+				delete(p.passes, key)
+			}
+		}
+		p.lastSweep = now
+	}
+	bucket, ok := p.passes[ip]
+	if !ok || now.Sub(bucket.start) >= window {
+		bucket = &outageBucket{start: now}
+		p.passes[ip] = bucket
+	}
+	if bucket.count >= p.Budget {
+		return false
+	}
+	bucket.count++
+	return true
+}
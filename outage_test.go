@@ -0,0 +1,88 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type OutageSuite struct{}
+
+var _ = Suite(&OutageSuite{})
+
+func (s *OutageSuite) TestAllow(c *C) {
+	var none *OutagePolicy
+	c.Check(none.allow("1.2.3.4"), Equals, false)
+	c.Check((&OutagePolicy{}).allow("1.2.3.4"), Equals, false)
+	c.Check((&OutagePolicy{Mode: FailOpen}).allow("1.2.3.4"), Equals, true)
+
+	now := time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	policy := &OutagePolicy{Mode: FailOpen, Budget: 2, now: func() time.Time { return now }}
+	c.Check(policy.allow("1.2.3.4"), Equals, true)
+	c.Check(policy.allow("1.2.3.4"), Equals, true)
+	c.Check(policy.allow("1.2.3.4"), Equals, false)
+	c.Check(policy.allow("5.6.7.8"), Equals, true)
+
+	now = now.Add(time.Minute)
+	c.Check(policy.allow("1.2.3.4"), Equals, true)
+	c.Check(policy.passes, HasLen, 1)
+
+	// no shared bucket for unknown clients
+	c.Check(policy.allow(""), Equals, false)
+	c.Check((&OutagePolicy{Mode: FailOpen}).allow(""), Equals, true)
+}
+
+func (s *OutageSuite) TestIsOutage(c *C) {
+	c.Check(isOutage(nil), Equals, false)
+	c.Check(isOutage(&Error{Reason: ReasonTransport, RequestError: true, err: errors.New("i/o timeout")}), Equals, true)
+	c.Check(isOutage(&Error{Reason: ReasonTransport, RequestError: true, StatusCode: 503}), Equals, true)
+	c.Check(isOutage(&Error{Reason: ReasonTransport, RequestError: true, StatusCode: 404}), Equals, false)
+	c.Check(isOutage(&Error{Reason: ReasonTransport, RequestError: true, err: context.Canceled}), Equals, false)
+	c.Check(isOutage(&Error{Reason: ReasonCircuitOpen, RequestError: true}), Equals, true)
+	c.Check(isOutage(&Error{Reason: ReasonDecode, RequestError: true}), Equals, false)
+	c.Check(isOutage(&Error{Reason: ReasonInvalidSolution}), Equals, false)
+}
+
+func (s *OutageSuite) TestFailOpen(c *C) {
+	captcha := ReCAPTCHA{
+		client: &mockSequenceClient{statuses: []int{503}},
+	}
+	err := captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+
+	captcha.Outage = &OutagePolicy{Mode: FailOpen, Budget: 1}
+	result, err := captcha.Check("mycode", VerifyOption{RemoteIP: "1.2.3.4"})
+	c.Assert(err, IsNil)
+	c.Check(result.FailedOpen, Equals, true)
+	c.Check(result.Decision, Equals, DecisionReview)
+	c.Check(errors.Is(result.OutageError, ErrTransport), Equals, true)
+
+	// budget spent
+	result, err = captcha.Check("mycode", VerifyOption{RemoteIP: "1.2.3.4"})
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+	c.Check(result.FailedOpen, Equals, false)
+
+	// garbage answers and canceled requests are not outages
+	captcha.client = &mockInvalidClient{}
+	_, err = captcha.Check("mycode", VerifyOption{RemoteIP: "5.6.7.8"})
+	c.Check(errors.Is(err, ErrDecode), Equals, true)
+	captcha.client = &mockContextClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = captcha.CheckContext(ctx, "mycode", VerifyOption{RemoteIP: "5.6.7.8"})
+	c.Check(errors.Is(err, context.Canceled), Equals, true)
+	c.Check(result.FailedOpen, Equals, false)
+
+	// requests without remote IP do not share a budget
+	captcha.client = &mockSequenceClient{statuses: []int{0}}
+	_, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+
+	// definitive answers never fail open
+	captcha.client = &mockInvalidSolutionClient{}
+	result, err = captcha.Check("mycode", VerifyOption{RemoteIP: "5.6.7.8"})
+	c.Check(errors.Is(err, ErrInvalidSolution), Equals, true)
+	c.Check(result.FailedOpen, Equals, false)
+}
@@ -0,0 +1,203 @@
+package recaptcha
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultPassCookie name of the human pass cookie
+const DefaultPassCookie = "recaptcha_pass"
+
+// HumanPass issues and accepts signed, expiring cookies proving the client recently passed verification,
+// so users doing several protected actions in a session are not challenged every time.
+// The pass is bound to the session returned by SessionID, or to the client IP when SessionID is nil
+type HumanPass struct {
+	// Key signs the cookies with HMAC-SHA256, it must be kept secret and at least 32 random bytes long,
+	// no pass is issued nor accepted with a shorter key.
+	Key []byte
+	// CookieName is DefaultPassCookie when empty.
+	CookieName string
+	// TTL is how long a pass is valid, 30 minutes when zero.
+	TTL time.Duration
+	// Actions lists the action patterns, as understood by path.Match, the pass is accepted for, every action when empty.
+	// A pass is only accepted for the action it was issued for, with a score reaching the threshold of the route.
+	Actions []string
+	// SessionID returns the session the pass is bound to, no pass is issued nor accepted without a session.
+	SessionID func(req *http.Request) string
+	// ClientIP resolves the client IP the pass is bound to when SessionID is nil.
+	ClientIP *ClientIPResolver
+	// Secure marks the cookie to be sent over https only.
+	Secure bool
+
+	now func() time.Time
+}
+
+// minPassKeyLength shortest HumanPass key accepted, shorter keys could be guessed to forge passes
+const minPassKeyLength = 32
+
+// NewHumanPass returns a HumanPass signing with key, which must be at least 32 random bytes long,
+// kept secret and shared by every instance checking the passes
+func NewHumanPass(key []byte) (*HumanPass, error) {
+	if len(key) < minPassKeyLength {
+		return nil, fmt.Errorf("human pass key must be at least %d bytes long", minPassKeyLength)
+	}
+	return &HumanPass{Key: key}, nil
+}
+
+// Pass content of a valid human pass cookie
+type Pass struct {
+	Score  float32   `json:"s"`
+	Action string    `json:"a"`
+	Expiry time.Time `json:"e"`
+}
+
+// Issue sets a pass cookie for a successful verification, nothing is issued for results not allowed outright,
+// including the ones only allowed by shadow mode
+func (h *HumanPass) Issue(w http.ResponseWriter, req *http.Request, result VerifyResult) {
+	if len(h.Key) < minPassKeyLength || result.Decision != DecisionAllow || result.FailedOpen || result.FromPass || result.ShadowError != nil {
+		return
+	}
+	binding, ok := h.binding(req)
+	if !ok {
+		return
+	}
+	expiry := h.clock().Add(h.ttl())
+	payload, err := json.Marshal(Pass{Score: result.Score, Action: result.Action, Expiry: expiry.Truncate(time.Second)})
+	if err != nil {
+		return
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookieName(),
+		Value:    encoded + "." + base64.RawURLEncoding.EncodeToString(h.sign(binding, encoded)),
+		Path:     "/",
+		Expires:  expiry,
+		HttpOnly: true,
+		Secure:   h.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Check returns the pass of req when it carries a valid one, issued for the same session and, when action is set,
+// for that action. Callers must still compare Pass.Score with the threshold of the route, the middleware does
+func (h *HumanPass) Check(req *http.Request, action string) (Pass, bool) {
+	var pass Pass
+	if len(h.Key) < minPassKeyLength || !h.accepts(action) {
+		return pass, false
+	}
+	binding, ok := h.binding(req)
+	if !ok {
+		return pass, false
+	}
+	cookie, err := req.Cookie(h.cookieName())
+	if err != nil {
+		return pass, false
+	}
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return pass, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, h.sign(binding, parts[0])) {
+		return pass, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(payload, &pass) != nil {
+		return pass, false
+	}
+	if !h.clock().Before(pass.Expiry) || (action != "" && pass.Action != action) {
+		return pass, false
+	}
+	return pass, true
+}
+
+// passThreshold returns the minimum score of a pass accepted in lieu of verifying with options,
+// the threshold of the options, else of the policy of their action, else DefaultThreshold
+func (r *ReCAPTCHA) passThreshold(options VerifyOption) float32 {
+	if r.Version != V3 {
+		return 0
+	}
+	if options.Threshold != 0 {
+		return options.Threshold
+	}
+	if policy, ok := r.Policies.Match(options.Action); ok && options.Action != "" {
+		if len(policy.Bands) > 0 {
+			return allowScore(policy.Bands)
+		}
+		if policy.Threshold != 0 {
+			return policy.Threshold
+		}
+	}
+	return DefaultThreshold
+}
+
+// allowScore returns the lowest score allowed outright by bands, above any score when none allows
+func allowScore(bands []ScoreBand) float32 {
+	var score float32 = 2
+	for _, band := range bands {
+		if band.Decision == DecisionAllow && band.MinScore < score {
+			score = band.MinScore
+		}
+	}
+	return score
+}
+
+// binding returns what passes of req are bound to, false when req has no session or client IP to bind to
+// as a pass bound to nothing would be valid for every such client
+func (h *HumanPass) binding(req *http.Request) (string, bool) {
+	if h.SessionID != nil {
+		session := h.SessionID(req)
+		return "session:" + session, session != ""
+	}
+	ip := h.ClientIP.Resolve(req)
+	return "ip:" + ip, ip != ""
+}
+
+// sign computes the signature of the encoded payload for binding
+func (h *HumanPass) sign(binding string, encoded string) []byte {
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write([]byte(encoded))
+	mac.Write([]byte{0})
+	mac.Write([]byte(binding))
+	return mac.Sum(nil)
+}
+
+func (h *HumanPass) accepts(action string) bool {
+	if len(h.Actions) == 0 {
+		return true
+	}
+	for _, pattern := range h.Actions {
+		if matched, err := path.Match(pattern, action); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HumanPass) cookieName() string {
+	if h.CookieName == "" {
+		return DefaultPassCookie
+	}
+	return h.CookieName
+}
+
+func (h *HumanPass) ttl() time.Duration {
+	if h.TTL <= 0 {
+		return 30 * time.Minute
+	}
+	return h.TTL
+}
+
+func (h *HumanPass) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
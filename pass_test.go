@@ -0,0 +1,191 @@
+package recaptcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PassSuite struct {
+	pass *HumanPass
+	now  time.Time
+}
+
+var _ = Suite(&PassSuite{})
+
+func (s *PassSuite) SetUpTest(c *C) {
+	s.now = time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	var err error
+	s.pass, err = NewHumanPass(stepUpKey)
+	c.Assert(err, IsNil)
+	s.pass.TTL, s.pass.Actions = 10*time.Minute, []string{"comment", "vote*"}
+	s.pass.now = func() time.Time { return s.now }
+}
+
+func (s *PassSuite) issue(c *C, result VerifyResult, remoteAddr string) *http.Cookie {
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	s.pass.Issue(rec, req, result)
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	c.Check(cookies[0].Name, Equals, DefaultPassCookie)
+	c.Check(cookies[0].HttpOnly, Equals, true)
+	return cookies[0]
+}
+
+func passRequest(cookie *http.Cookie, remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.RemoteAddr = remoteAddr
+	req.AddCookie(cookie)
+	return req
+}
+
+func (s *PassSuite) TestNewHumanPass(c *C) {
+	_, err := NewHumanPass(nil)
+	c.Check(err, ErrorMatches, "human pass key must be at least 32 bytes long")
+	_, err = NewHumanPass(stepUpKey[:31])
+	c.Check(err, NotNil)
+
+	cookie := s.issue(c, VerifyResult{Success: true, Decision: DecisionAllow, Action: "comment"}, "192.0.2.1:1234")
+	c.Assert(cookie, NotNil)
+	weak := &HumanPass{Key: []byte("short")}
+	c.Check(s.issueWith(weak, VerifyResult{Success: true, Decision: DecisionAllow, Action: "comment"}), HasLen, 0)
+	_, ok := weak.Check(passRequest(cookie, "192.0.2.1:1234"), "comment")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PassSuite) issueWith(pass *HumanPass, result VerifyResult) []*http.Cookie {
+	rec := httptest.NewRecorder()
+	pass.Issue(rec, httptest.NewRequest(http.MethodPost, "/submit", nil), result)
+	return rec.Result().Cookies()
+}
+
+func (s *PassSuite) TestIssue(c *C) {
+	c.Check(s.issue(c, VerifyResult{Success: true, Decision: DecisionReview}, "192.0.2.1:1234"), IsNil)
+	c.Check(s.issue(c, VerifyResult{Decision: DecisionAllow, FailedOpen: true}, "192.0.2.1:1234"), IsNil)
+	c.Check(s.issue(c, VerifyResult{Decision: DecisionAllow, FromPass: true}, "192.0.2.1:1234"), IsNil)
+
+	cookie := s.issue(c, VerifyResult{Success: true, Decision: DecisionAllow, Score: 0.9, Action: "comment"}, "192.0.2.1:1234")
+	c.Assert(cookie, NotNil)
+
+	pass, ok := s.pass.Check(passRequest(cookie, "192.0.2.1:4321"), "comment")
+	c.Check(ok, Equals, true)
+	c.Check(pass.Score, Equals, float32(0.9))
+	c.Check(pass.Action, Equals, "comment")
+	c.Check(pass.Expiry.Equal(s.now.Add(10*time.Minute)), Equals, true)
+
+	_, ok = s.pass.Check(passRequest(cookie, "192.0.2.1:4321"), "vote_up")
+	c.Check(ok, Equals, false)
+	_, ok = s.pass.Check(passRequest(cookie, "192.0.2.1:4321"), "login")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PassSuite) TestCheckRejects(c *C) {
+	cookie := s.issue(c, VerifyResult{Success: true, Decision: DecisionAllow, Score: 0.9, Action: "comment"}, "192.0.2.1:1234")
+	c.Assert(cookie, NotNil)
+
+	_, ok := s.pass.Check(passRequest(cookie, "192.0.2.2:1234"), "comment")
+	c.Check(ok, Equals, false)
+
+	tampered := *cookie
+	tampered.Value = "x" + cookie.Value[1:]
+	_, ok = s.pass.Check(passRequest(&tampered, "192.0.2.1:1234"), "comment")
+	c.Check(ok, Equals, false)
+
+	_, ok = s.pass.Check(httptest.NewRequest(http.MethodPost, "/submit", nil), "comment")
+	c.Check(ok, Equals, false)
+
+	s.now = s.now.Add(10 * time.Minute)
+	_, ok = s.pass.Check(passRequest(cookie, "192.0.2.1:1234"), "comment")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PassSuite) TestSessionBinding(c *C) {
+	s.pass.SessionID = func(req *http.Request) string { return req.Header.Get("X-Session") }
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-Session", "abc")
+	rec := httptest.NewRecorder()
+	s.pass.Issue(rec, req, VerifyResult{Success: true, Decision: DecisionAllow, Action: "comment"})
+	cookie := rec.Result().Cookies()[0]
+
+	req = passRequest(cookie, "192.0.2.9:1234")
+	req.Header.Set("X-Session", "abc")
+	_, ok := s.pass.Check(req, "comment")
+	c.Check(ok, Equals, true)
+
+	req = passRequest(cookie, "192.0.2.9:1234")
+	req.Header.Set("X-Session", "def")
+	_, ok = s.pass.Check(req, "comment")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PassSuite) TestNoSession(c *C) {
+	s.pass.SessionID = func(req *http.Request) string { return req.Header.Get("X-Session") }
+	c.Check(s.issue(c, VerifyResult{Success: true, Decision: DecisionAllow, Action: "comment"}, "192.0.2.1:1234"), IsNil)
+
+	// a cookie signed without session must not be shared by sessionless clients
+	withIP := &HumanPass{Key: s.pass.Key, now: s.pass.now}
+	cookie := s.issueWith(withIP, VerifyResult{Success: true, Decision: DecisionAllow, Action: "comment"})[0]
+	_, ok := s.pass.Check(passRequest(cookie, "192.0.2.1:1234"), "comment")
+	c.Check(ok, Equals, false)
+	_, ok = s.pass.Check(passRequest(cookie, "192.0.2.2:1234"), "comment")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PassSuite) TestMiddlewarePass(c *C) {
+	client := &mockBodyClient{body: v3Body("0.9")}
+	captcha := ReCAPTCHA{client: client, Version: V3}
+	var got VerifyResult
+	handler := captcha.Middleware(WithVerifyOption(VerifyOption{Action: "comment"}), WithHumanPass(s.pass))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, _ = ResultFromContext(req.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	c.Check(got.FromPass, Equals, false)
+	cookies := rec.Result().Cookies()
+	c.Assert(cookies, HasLen, 1)
+
+	client.request = nil
+	req := formRequest(url.Values{})
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	c.Check(got.FromPass, Equals, true)
+	c.Check(got.Score, Equals, float32(0.9))
+	c.Check(client.request, IsNil)
+	c.Check(rec.Result().Cookies(), HasLen, 0)
+}
+
+func (s *PassSuite) TestMiddlewarePassThreshold(c *C) {
+	client := &mockBodyClient{body: v3Body("0.3")}
+	captcha := ReCAPTCHA{client: client, Version: V3, Policies: PolicyTable{{Action: "comment", Threshold: 0.9}}}
+	lenient := captcha.Middleware(WithVerifyOption(VerifyOption{Action: "comment", Threshold: 0.2}), WithHumanPass(s.pass))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	lenient.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	cookies := rec.Result().Cookies()
+	c.Assert(cookies, HasLen, 1)
+
+	strict := captcha.Middleware(WithVerifyOption(VerifyOption{Action: "comment"}), WithHumanPass(s.pass))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Error("handler must not be called")
+	}))
+	client.request = nil
+	req := formRequest(url.Values{DefaultTokenField: {"mycode"}})
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	strict.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+	c.Check(client.request, NotNil)
+}
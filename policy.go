@@ -0,0 +1,144 @@
+package recaptcha
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+)
+
+// ActionPolicy verification options of the actions it matches
+type ActionPolicy struct {
+	// Action is a glob pattern as understood by path.Match, like "checkout" or "admin_*", ignored when Regexp is set.
+	Action string
+	Regexp *regexp.Regexp
+	// Threshold, Hostname and ResponseTime are used when not set in the VerifyOption of the call.
+	Threshold    float32
+	Hostname     string
+	ResponseTime time.Duration
+	// Bands replace Threshold with graduated decisions when set, see ScoreBand.
+	Bands []ScoreBand
+	// Shadow evaluates the checks of the policy without enforcing them, see ReCAPTCHA.Shadow.
+	Shadow bool
+}
+
+// Matches reports whether the policy applies to action
+func (p ActionPolicy) Matches(action string) bool {
+	if p.Regexp != nil {
+		return p.Regexp.MatchString(action)
+	}
+	matched, err := path.Match(p.Action, action)
+	return err == nil && matched
+}
+
+// PolicyTable action policies in order of precedence, the first matching policy applies
+type PolicyTable []ActionPolicy
+
+// Match returns the first policy matching action
+func (t PolicyTable) Match(action string) (ActionPolicy, bool) {
+	for _, policy := range t {
+		if policy.Matches(action) {
+			return policy, true
+		}
+	}
+	return ActionPolicy{}, false
+}
+
+// applyPolicy fills the options left unset with the policy of the expected action.
+// Without an expected action the action of the token, chosen by the client, picks the policy
+// so a client may pick the most lenient one: set VerifyOption.Action when policies differ in strictness
+func (r *ReCAPTCHA) applyPolicy(result *VerifyResult, options VerifyOption) VerifyOption {
+	action := options.Action
+	if action == "" {
+		action = result.Action
+	}
+	if action == "" {
+		return options
+	}
+	policy, ok := r.Policies.Match(action)
+	if !ok {
+		return options
+	}
+	result.Policy = &policy
+	if options.Threshold == 0 {
+		options.Threshold = policy.Threshold
+	}
+	if options.Hostname == "" {
+		options.Hostname = policy.Hostname
+	}
+	if options.ResponseTime == 0 {
+		options.ResponseTime = policy.ResponseTime
+	}
+	return options
+}
+
+// Decision how to react to a verified request
+type Decision int8
+
+const (
+	// DecisionDeny reject the request
+	DecisionDeny Decision = iota
+	// DecisionChallenge ask for more proof, like a V2 checkbox challenge, before accepting the request
+	DecisionChallenge
+	// DecisionReview accept the request but flag it for later review
+	DecisionReview
+	// DecisionAllow accept the request
+	DecisionAllow
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionDeny:
+		return "deny"
+	case DecisionChallenge:
+		return "challenge"
+	case DecisionReview:
+		return "review"
+	case DecisionAllow:
+		return "allow"
+	}
+	return "unknown"
+}
+
+// ScoreBand scores from MinScore up to the MinScore of the next band lead to Decision,
+// scores below every band are denied
+type ScoreBand struct {
+	MinScore float32
+	Decision Decision
+}
+
+// shadow lets a solved challenge failing its checks through when shadow mode applies,
+// the error it would have failed with is kept in result.ShadowError
+func (r *ReCAPTCHA) shadow(result *VerifyResult, err error) error {
+	if err == nil || !result.Success || len(result.ErrorCodes) > 0 {
+		return err
+	}
+	if !r.Shadow && (result.Policy == nil || !result.Policy.Shadow) {
+		return err
+	}
+	result.ShadowError, result.Decision = err, DecisionAllow
+	return nil
+}
+
+// applyBands sets result.Decision from the score bands of result.Policy, denied requests fail with
+// ErrScoreBelowThreshold and the ones to challenge with ErrChallengeRequired
+func applyBands(result *VerifyResult) error {
+	decision := DecisionDeny
+	var best *ScoreBand
+	for i, band := range result.Policy.Bands {
+		if band.MinScore <= result.Score && (best == nil || band.MinScore > best.MinScore) {
+			best = &result.Policy.Bands[i]
+		}
+	}
+	if best != nil {
+		decision = best.Decision
+	}
+	result.Decision = decision
+	switch decision {
+	case DecisionDeny:
+		return &Error{msg: fmt.Sprintf("received score '%f', denied for action '%s'", result.Score, result.Action), Reason: ReasonScoreBelowThreshold}
+	case DecisionChallenge:
+		return &Error{msg: fmt.Sprintf("received score '%f', challenge required for action '%s'", result.Score, result.Action), Reason: ReasonChallengeRequired}
+	}
+	return nil
+}
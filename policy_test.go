@@ -0,0 +1,149 @@
+package recaptcha
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PolicySuite struct{}
+
+var _ = Suite(&PolicySuite{})
+
+func (s *PolicySuite) TestMatch(c *C) {
+	table := PolicyTable{
+		{Action: "login", Threshold: 0.7},
+		{Regexp: regexp.MustCompile(`^(checkout|pay)_`), Threshold: 0.9},
+		{Action: "admin_*", Threshold: 0.8},
+		{Action: "*", Threshold: 0.3},
+	}
+	cases := map[string]float32{
+		"login":          0.7,
+		"checkout_cart":  0.9,
+		"pay_now":        0.9,
+		"admin_settings": 0.8,
+		"comment":        0.3,
+	}
+	for action, threshold := range cases {
+		policy, ok := table.Match(action)
+		c.Check(ok, Equals, true)
+		c.Check(policy.Threshold, Equals, threshold, Commentf(action))
+	}
+	_, ok := table[:3].Match("comment")
+	c.Check(ok, Equals, false)
+	_, ok = PolicyTable{{Action: "[bad"}}.Match("bad")
+	c.Check(ok, Equals, false)
+}
+
+func (s *PolicySuite) TestApplyPolicy(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockV3FullResponseClient{}, // action homepage, score 0.3, hostname test2.com
+		horloge: &mockClockOverRespenseTime{},
+		Version: V3,
+		Policies: PolicyTable{
+			{Action: "home*", Threshold: 0.2, Hostname: "test2.com"},
+			{Action: "login", Threshold: 0.9},
+		},
+	}
+	result, err := captcha.Check("mycode", VerifyOption{})
+	c.Assert(err, IsNil)
+	c.Assert(result.Policy, NotNil)
+	c.Check(result.Policy.Action, Equals, "home*")
+	c.Check(result.Passed(CheckHostname), Equals, true)
+	c.Check(result.Passed(CheckThreshold), Equals, true)
+
+	// explicit options win
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{Threshold: 0.5})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{Hostname: "test.com"})
+	c.Check(errors.Is(err, ErrHostnameMismatch), Equals, true)
+
+	captcha.Policies[0].ResponseTime = 5 * time.Second
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrResponseTooSlow), Equals, true)
+
+	// the policy of the expected action applies, not the lenient one of the token action
+	captcha.Policies[0].ResponseTime = 0
+	result, err = captcha.Check("mycode", VerifyOption{Action: "login"})
+	c.Check(errors.Is(err, ErrActionMismatch), Equals, true)
+	c.Check(result.Policy.Action, Equals, "login")
+	c.Check(result.Passed(CheckThreshold), Equals, false)
+	c.Check(result.Checks[len(result.Checks)-1].Expected, Equals, "0.900000")
+
+	captcha.Policies = nil
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+	c.Check(result.Policy, IsNil)
+}
+
+func v3Body(score string) string {
+	return `{"success": true, "challenge_ts": "2018-03-06T03:41:29+00:00", "action": "comment", "score": ` + score + `}`
+}
+
+func (s *PolicySuite) TestBands(c *C) {
+	client := &mockBodyClient{}
+	captcha := ReCAPTCHA{
+		client:  client,
+		Version: V3,
+		Policies: PolicyTable{{Action: "comment", Bands: []ScoreBand{
+			{MinScore: 0.7, Decision: DecisionAllow},
+			{MinScore: 0.2, Decision: DecisionChallenge},
+			{MinScore: 0.5, Decision: DecisionReview},
+		}}},
+	}
+	cases := []struct {
+		score    string
+		decision Decision
+		err      error
+	}{
+		{"0.9", DecisionAllow, nil},
+		{"0.7", DecisionAllow, nil},
+		{"0.6", DecisionReview, nil},
+		{"0.3", DecisionChallenge, ErrChallengeRequired},
+		{"0.1", DecisionDeny, ErrScoreBelowThreshold},
+	}
+	for _, t := range cases {
+		client.body = v3Body(t.score)
+		result, err := captcha.Check("mycode", VerifyOption{})
+		c.Check(result.Decision, Equals, t.decision, Commentf(t.score))
+		if t.err == nil {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(errors.Is(err, t.err), Equals, true, Commentf(t.score))
+		}
+		// bands replace the threshold check
+		c.Check(result.Passed(CheckThreshold), Equals, false)
+	}
+	client.body = v3Body("0.3")
+	err := captcha.Verify("mycode")
+	c.Check(err, ErrorMatches, "received score '0.300000', challenge required for action 'comment'")
+
+	// other checks still apply first
+	result, err := captcha.Check("mycode", VerifyOption{Hostname: "test.com"})
+	c.Check(errors.Is(err, ErrHostnameMismatch), Equals, true)
+	c.Check(result.Decision, Equals, DecisionDeny)
+
+	// a threshold set by the caller still applies before the bands
+	client.body = v3Body("0.75")
+	result, err = captcha.Check("mycode", VerifyOption{Threshold: 0.9})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+	c.Check(result.Decision, Equals, DecisionDeny)
+	result, err = captcha.Check("mycode", VerifyOption{Threshold: 0.6})
+	c.Check(err, IsNil)
+	c.Check(result.Decision, Equals, DecisionAllow)
+	c.Check(result.Passed(CheckThreshold), Equals, true)
+
+	// without bands the decision follows the threshold
+	captcha.Policies = nil
+	client.body = v3Body("0.3")
+	result, err = captcha.Check("mycode", VerifyOption{Threshold: 0.2})
+	c.Check(err, IsNil)
+	c.Check(result.Decision, Equals, DecisionAllow)
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(result.Decision, Equals, DecisionDeny)
+
+	c.Check(DecisionReview.String(), Equals, "review")
+	c.Check(Decision(42).String(), Equals, "unknown")
+}
@@ -0,0 +1,226 @@
+package recaptcha
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets upper bounds in seconds of the PrometheusObserver latency histogram
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// scoreBuckets upper bounds of the PrometheusObserver score histogram
+var scoreBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// defaultMaxActions number of action labels kept by a PrometheusObserver without MaxActions
+const defaultMaxActions = 100
+
+// otherAction replaces the actions seen past PrometheusObserver.MaxActions in labels
+const otherAction = "other"
+
+// PrometheusObserver is an Observer counting verifications and exposing them in the Prometheus text format,
+// it is an http.Handler to be mounted on the metrics endpoint. The zero value is ready to use.
+// The action label is taken from responses, it is bounded by MaxActions so clients cannot grow the series without limit
+type PrometheusObserver struct {
+	// LatencyBuckets are the latency histogram upper bounds in seconds, in increasing order, DefaultLatencyBuckets when nil.
+	LatencyBuckets []float64
+	// MaxActions is the number of distinct action labels kept, later actions are counted as "other", 100 when zero.
+	MaxActions int
+
+	mu            sync.Mutex
+	verifications map[[3]string]uint64
+	shadows       map[[3]string]uint64
+	responses     map[int]uint64
+	latency       map[string]*histogram
+	scores        map[string]*histogram
+	actions       map[string]bool
+	breaker       BreakerState
+	transitions   map[BreakerState]uint64
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, value float64) {
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// NewPrometheusObserver returns a PrometheusObserver using DefaultLatencyBuckets and keeping 100 action labels
+func NewPrometheusObserver() *PrometheusObserver {
+	return &PrometheusObserver{
+		LatencyBuckets: DefaultLatencyBuckets,
+		MaxActions:     defaultMaxActions,
+	}
+}
+
+// init allocates the counters on first use, it is called with p.mu held
+func (p *PrometheusObserver) init() {
+	if p.verifications != nil {
+		return
+	}
+	p.verifications = map[[3]string]uint64{}
+	p.shadows = map[[3]string]uint64{}
+	p.responses = map[int]uint64{}
+	p.latency = map[string]*histogram{}
+	p.scores = map[string]*histogram{}
+	p.actions = map[string]bool{}
+	p.transitions = map[BreakerState]uint64{}
+}
+
+func (p *PrometheusObserver) latencyBuckets() []float64 {
+	if p.LatencyBuckets == nil {
+		return DefaultLatencyBuckets
+	}
+	return p.LatencyBuckets
+}
+
+// Observe records o
+func (p *PrometheusObserver) Observe(o Observation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.init()
+	action := p.action(o.Action)
+	version := o.Version.String()
+	p.verifications[[3]string{version, action, o.Reason.String()}]++
+	if o.ShadowReason != ReasonNone {
+		p.shadows[[3]string{version, action, o.ShadowReason.String()}]++
+	}
+	if o.StatusCode != 0 {
+		p.responses[o.StatusCode]++
+	}
+	buckets := p.latencyBuckets()
+	if p.latency[version] == nil {
+		p.latency[version] = &histogram{counts: make([]uint64, len(buckets))}
+	}
+	p.latency[version].observe(buckets, o.Latency.Seconds())
+	if o.Version == V3 && o.StatusCode != 0 && o.Reason != ReasonTransport && o.Reason != ReasonDecode {
+		if p.scores[action] == nil {
+			p.scores[action] = &histogram{counts: make([]uint64, len(scoreBuckets))}
+		}
+		// go through the shortest decimal form so 0.1 lands in the 0.1 bucket
+		score, _ := strconv.ParseFloat(strconv.FormatFloat(float64(o.Score), 'f', -1, 32), 64)
+		p.scores[action].observe(scoreBuckets, score)
+	}
+}
+
+// ObserveBreaker records a CircuitBreaker transition, it is meant to be set as CircuitBreaker.OnStateChange
+func (p *PrometheusObserver) ObserveBreaker(from, to BreakerState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.init()
+	p.breaker = to
+	p.transitions[to]++
+}
+
+func (p *PrometheusObserver) action(action string) string {
+	if action == "" || p.actions[action] {
+		return action
+	}
+	max := p.MaxActions
+	if max <= 0 {
+		max = defaultMaxActions
+	}
+	if len(p.actions) >= max {
+		return otherAction
+	}
+	p.actions[action] = true
+	return action
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (p *PrometheusObserver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b strings.Builder
+	p.mu.Lock()
+	p.write(&b)
+	p.mu.Unlock()
+	w.Write([]byte(b.String()))
+}
+
+func (p *PrometheusObserver) write(b *strings.Builder) {
+	b.WriteString("# HELP recaptcha_verifications_total Verifications sent to recaptcha by outcome reason.\n")
+	b.WriteString("# TYPE recaptcha_verifications_total counter\n")
+	writeReasonCounters(b, "recaptcha_verifications_total", p.verifications)
+
+	b.WriteString("# HELP recaptcha_shadow_failures_total Verifications let through by shadow mode by the reason they would have failed for.\n")
+	b.WriteString("# TYPE recaptcha_shadow_failures_total counter\n")
+	writeReasonCounters(b, "recaptcha_shadow_failures_total", p.shadows)
+
+	b.WriteString("# HELP recaptcha_responses_total Answers received from recaptcha by http status.\n")
+	b.WriteString("# TYPE recaptcha_responses_total counter\n")
+	statuses := make([]int, 0, len(p.responses))
+	for status := range p.responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(b, "recaptcha_responses_total{status=\"%d\"} %d\n", status, p.responses[status])
+	}
+
+	b.WriteString("# HELP recaptcha_verification_duration_seconds Time spent verifying, retries included.\n")
+	b.WriteString("# TYPE recaptcha_verification_duration_seconds histogram\n")
+	writeHistograms(b, "recaptcha_verification_duration_seconds", "version", p.latency, p.latencyBuckets())
+
+	b.WriteString("# HELP recaptcha_score Scores received from recaptcha v3.\n")
+	b.WriteString("# TYPE recaptcha_score histogram\n")
+	writeHistograms(b, "recaptcha_score", "action", p.scores, scoreBuckets)
+
+	b.WriteString("# HELP recaptcha_breaker_state Circuit breaker state, 0 closed, 1 open, 2 half-open.\n")
+	b.WriteString("# TYPE recaptcha_breaker_state gauge\n")
+	fmt.Fprintf(b, "recaptcha_breaker_state %d\n", p.breaker)
+	b.WriteString("# HELP recaptcha_breaker_transitions_total Circuit breaker transitions by new state.\n")
+	b.WriteString("# TYPE recaptcha_breaker_transitions_total counter\n")
+	for _, state := range []BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen} {
+		fmt.Fprintf(b, "recaptcha_breaker_transitions_total{state=\"%s\"} %d\n", state, p.transitions[state])
+	}
+}
+
+func writeReasonCounters(b *strings.Builder, name string, counters map[[3]string]uint64) {
+	keys := make([][3]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i][:], "\x00") < strings.Join(keys[j][:], "\x00")
+	})
+	for _, key := range keys {
+		fmt.Fprintf(b, "%s{version=\"%s\",action=\"%s\",reason=\"%s\"} %d\n",
+			name, key[0], escapeLabel(key[1]), key[2], counters[key])
+	}
+}
+
+func writeHistograms(b *strings.Builder, name string, label string, histograms map[string]*histogram, buckets []float64) {
+	values := make([]string, 0, len(histograms))
+	for value := range histograms {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	for _, value := range values {
+		h := histograms[value]
+		value = escapeLabel(value)
+		for i, bound := range buckets {
+			fmt.Fprintf(b, "%s_bucket{%s=\"%s\",le=\"%s\"} %d\n", name, label, value, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{%s=\"%s\",le=\"+Inf\"} %d\n", name, label, value, h.count)
+		fmt.Fprintf(b, "%s_sum{%s=\"%s\"} %s\n", name, label, value, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{%s=\"%s\"} %d\n", name, label, value, h.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
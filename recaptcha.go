@@ -1,9 +1,9 @@
 package recaptcha
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -23,6 +23,16 @@ const (
 	DefaultThreshold float32 = 0.5
 )
 
+func (v VERSION) String() string {
+	switch v {
+	case V2:
+		return "v2"
+	case V3:
+		return "v3"
+	}
+	return "unknown"
+}
+
 type reCHAPTCHARequest struct {
 	Secret   string `json:"secret"`
 	Response string `json:"response"`
@@ -41,7 +51,7 @@ type reCHAPTCHAResponse struct {
 
 // custom client so we can mock in tests
 type netClient interface {
-	PostForm(url string, formValues url.Values) (resp *http.Response, err error)
+	Do(req *http.Request) (resp *http.Response, err error)
 }
 
 // custom clock so we can mock in tests
@@ -63,19 +73,98 @@ type ReCAPTCHA struct {
 	ReCAPTCHALink string
 	Version       VERSION
 	Timeout       time.Duration
-	horloge       clock
+	// TokenSource tells VerifyRequest and Middleware where to read the challenge response from,
+	// the `g-recaptcha-response` form field is used when nil.
+	TokenSource TokenSource
+	// ClientIP resolves the RemoteIP of requests verified by VerifyRequest and Middleware,
+	// no proxy is trusted when nil.
+	ClientIP *ClientIPResolver
+	// ReplayStore rejects already verified challenge responses locally, without asking recaptcha, when set.
+	ReplayStore ReplayStore
+	// Retry retries the request to recaptcha on transient failures when set.
+	Retry *RetryPolicy
+	// Outage tells what to do when recaptcha cannot be reached, fail closed when nil.
+	Outage *OutagePolicy
+	// Breaker stops calling recaptcha for a while when too many requests fail, when set.
+	Breaker *CircuitBreaker
+	// Policies holds the options of each action, applied to the tokens of a known action.
+	Policies PolicyTable
+	// Observer is notified of every verification sent to recaptcha, when set.
+	Observer Observer
+	// Tracer traces every verification sent to recaptcha, NoopTracer when nil.
+	Tracer Tracer
+	// Logger records every verification when set, the secret and challenge responses are never logged.
+	Logger *slog.Logger
+	// Audit receives a record of every verification decision when set.
+	Audit AuditSink
+	// Shadow evaluates every check without enforcing them: solved challenges failing a check are allowed,
+	// the error they would have failed with being reported in VerifyResult.ShadowError. ActionPolicy.Shadow
+	// enables it for the actions of a policy only.
+	Shadow  bool
+	horloge clock
 }
 
-// Error custom error to pass ErrorCodes and RequestError to user.
-type Error struct {
-	msg string
-	// ErrorCodes contains any error codes from the recaptcha response.
-	ErrorCodes []string
-	// RequestError is true if the verify request to recaptcha failed.
-	RequestError bool
+// Names of the checks reported in VerifyResult.Checks
+const (
+	CheckHostname       = "hostname"
+	CheckApkPackageName = "apk_package_name"
+	CheckResponseTime   = "response_time"
+	CheckAction         = "action"
+	CheckCData          = "cdata"
+	CheckThreshold      = "threshold"
+)
+
+// CheckResult outcome of a single VerifyOption check
+type CheckResult struct {
+	// Name is one of the Check* constants.
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Expected and Received are the option value and the value found in the recaptcha response.
+	Expected string `json:"expected"`
+	Received string `json:"received"`
 }
 
-func (e *Error) Error() string { return e.msg }
+// VerifyResult decoded recaptcha response along with the outcome of the requested option checks
+type VerifyResult struct {
+	Success        bool
+	ChallengeTS    time.Time
+	Hostname       string
+	ApkPackageName string
+	Action         string
+	Score          float32
+	CData          string // Turnstile only
+	ErrorCodes     []string
+	// AssessmentName and Reasons are only set by Enterprise, AssessmentName identifies the assessment to annotate.
+	AssessmentName string
+	Reasons        []string
+	// Attempts is the number of requests sent to recaptcha, more than one when retried.
+	Attempts int
+	// FailedOpen is true when recaptcha could not be reached and the OutagePolicy let the request through
+	// without verification, OutageError holds the error that was ignored.
+	FailedOpen  bool
+	OutageError error
+	// Policy is the action policy applied, if any.
+	Policy *ActionPolicy
+	// Decision tells how to react to the request, DecisionAllow or DecisionDeny unless the policy defines score bands,
+	// requests let through by the OutagePolicy are to be reviewed.
+	Decision Decision
+	// FromPass is true when the request was accepted on a HumanPass cookie, without verifying a challenge response.
+	FromPass bool
+	// Checks lists the option checks evaluated, in evaluation order.
+	Checks []CheckResult
+	// ShadowError is the error the verification would have failed with, had shadow mode not been enabled.
+	ShadowError error
+}
+
+// Passed returns true if the check with the given name was evaluated and passed
+func (v VerifyResult) Passed(name string) bool {
+	for _, check := range v.Checks {
+		if check.Name == name {
+			return check.Passed
+		}
+	}
+	return false
+}
 
 // NewReCAPTCHA new ReCAPTCHA instance if version is set to V2 uses recatpcha v2 API, get your secret from https://www.google.com/recaptcha/admin
 //  if version is set to V2 uses recatpcha v2 API, get your secret from https://g.co/recaptcha/v3
@@ -97,8 +186,14 @@ func NewReCAPTCHA(ReCAPTCHASecret string, version VERSION, timeout time.Duration
 
 // Verify returns `nil` if no error and the client solved the challenge correctly
 func (r *ReCAPTCHA) Verify(challengeResponse string) error {
-	body := reCHAPTCHARequest{Secret: r.Secret, Response: challengeResponse}
-	return r.confirm(body, VerifyOption{})
+	return r.VerifyContext(context.Background(), challengeResponse)
+}
+
+// VerifyContext is like Verify but the request to recaptcha is bound to ctx,
+// it is aborted as soon as ctx is canceled or its deadline is exceeded
+func (r *ReCAPTCHA) VerifyContext(ctx context.Context, challengeResponse string) error {
+	_, err := r.CheckContext(ctx, challengeResponse, VerifyOption{})
+	return err
 }
 
 // VerifyOption verification options expected for the challenge
@@ -109,21 +204,67 @@ type VerifyOption struct {
 	ApkPackageName string
 	ResponseTime   time.Duration
 	RemoteIP       string
+	CData          string // Turnstile only
+	IdempotencyKey string // Turnstile only
+	UserAgent      string // Enterprise only
 }
 
 // VerifyWithOptions returns `nil` if no error and the client solved the challenge correctly and all options are matching
 // `Threshold` and `Action` are ignored when using V2 version
 func (r *ReCAPTCHA) VerifyWithOptions(challengeResponse string, options VerifyOption) error {
+	return r.VerifyWithOptionsContext(context.Background(), challengeResponse, options)
+}
+
+// VerifyWithOptionsContext is like VerifyWithOptions but the request to recaptcha is bound to ctx,
+// it is aborted as soon as ctx is canceled or its deadline is exceeded
+func (r *ReCAPTCHA) VerifyWithOptionsContext(ctx context.Context, challengeResponse string, options VerifyOption) error {
+	_, err := r.CheckContext(ctx, challengeResponse, options)
+	return err
+}
+
+// Check is like VerifyWithOptions but also returns the decoded recaptcha response
+// and the outcome of every option check, the result is filled as far as the verification went
+// so it can be logged even when an error is returned
+func (r *ReCAPTCHA) Check(challengeResponse string, options VerifyOption) (VerifyResult, error) {
+	return r.CheckContext(context.Background(), challengeResponse, options)
+}
+
+// CheckContext is like Check but the request to recaptcha is bound to ctx,
+// it is aborted as soon as ctx is canceled or its deadline is exceeded
+func (r *ReCAPTCHA) CheckContext(ctx context.Context, challengeResponse string, options VerifyOption) (Result VerifyResult, Err error) {
+	if r.Logger != nil {
+		start := time.Now()
+		defer func() {
+			r.logVerification(ctx, challengeResponse, Result, Err, time.Since(start))
+		}()
+	}
+	if r.Audit != nil {
+		defer func() {
+			r.audit(challengeResponse, options, Result, Err)
+		}()
+	}
 	var body reCHAPTCHARequest
 	if options.RemoteIP == "" {
 		body = reCHAPTCHARequest{Secret: r.Secret, Response: challengeResponse}
 	} else {
 		body = reCHAPTCHARequest{Secret: r.Secret, Response: challengeResponse, RemoteIP: options.RemoteIP}
 	}
-	return r.confirm(body, options)
+	if err := r.claimToken(challengeResponse); err != nil {
+		return VerifyResult{}, err
+	}
+	result, err := r.confirm(ctx, body, options)
+	if recaptchaErr, ok := err.(*Error); ok && recaptchaErr.RequestError {
+		// recaptcha did not see the token, let it be verified again
+		r.releaseToken(challengeResponse)
+		if isOutage(err) && r.Outage.allow(options.RemoteIP) {
+			result.FailedOpen, result.OutageError, result.Decision = true, err, DecisionReview
+			return result, nil
+		}
+	}
+	return result, err
 }
 
-func (r *ReCAPTCHA) confirm(recaptcha reCHAPTCHARequest, options VerifyOption) (Err error) {
+func (r *ReCAPTCHA) confirm(ctx context.Context, recaptcha reCHAPTCHARequest, options VerifyOption) (Result VerifyResult, Err error) {
 	Err = nil
 	var formValues url.Values
 	if recaptcha.RemoteIP != "" {
@@ -131,69 +272,69 @@ func (r *ReCAPTCHA) confirm(recaptcha reCHAPTCHARequest, options VerifyOption) (
 	} else {
 		formValues = url.Values{"secret": {recaptcha.Secret}, "response": {recaptcha.Response}}
 	}
-	response, err := r.client.PostForm(r.ReCAPTCHALink, formValues)
-	if err != nil {
-		Err = &Error{msg: fmt.Sprintf("error posting to recaptcha endpoint: '%s'", err), RequestError: true}
-		return
-	}
-	defer response.Body.Close()
-	resultBody, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		Err = &Error{msg: fmt.Sprintf("couldn't read response body: '%s'", err), RequestError: true}
-		return
-	}
 	var result reCHAPTCHAResponse
-	err = json.Unmarshal(resultBody, &result)
-	if err != nil {
-		Err = &Error{msg: fmt.Sprintf("invalid response body json: '%s'", err), RequestError: true}
-		return
-	}
-
-	if result.ErrorCodes != nil {
-		Err = &Error{msg: fmt.Sprintf("remote error codes: %v", result.ErrorCodes), ErrorCodes: result.ErrorCodes}
-		return
-	}
-
-	if !result.Success && recaptcha.RemoteIP != "" {
-		Err = &Error{msg: fmt.Sprintf("invalid challenge solution or remote IP")}
-		return
+	ctx, span := r.tracer().Start(ctx, SpanName)
+	defer func() {
+		span.SetAttribute(AttributeVersion, r.Version.String())
+		span.SetAttribute(AttributeAction, Result.Action)
+		span.SetAttribute(AttributeScore, float64(Result.Score))
+		span.SetAttribute(AttributeHostname, Result.Hostname)
+		span.SetAttribute(AttributeReason, reasonOf(Err).String())
+		span.SetAttribute(AttributeAttempts, Result.Attempts)
+		span.End(Err)
+	}()
+	client := &instrumentedClient{client: r.client, tracer: r.tracer()}
+	if r.Observer != nil {
+		start := time.Now()
+		defer func() {
+			r.Observer.Observe(Observation{
+				Version:      r.Version,
+				Action:       Result.Action,
+				Hostname:     Result.Hostname,
+				Score:        Result.Score,
+				Reason:       reasonOf(Err),
+				ShadowReason: reasonOf(Result.ShadowError),
+				Decision:     Result.Decision,
+				StatusCode:   client.statusCode,
+				Latency:      time.Since(start),
+				Attempts:     Result.Attempts,
+			})
+		}()
 	}
-
-	if !result.Success {
-		Err = &Error{msg: fmt.Sprintf("invalid challenge solution")}
-		return
-	}
-
-	if options.Hostname != "" && options.Hostname != result.Hostname {
-		Err = &Error{msg: fmt.Sprintf("invalid response hostname '%s', while expecting '%s'", result.Hostname, options.Hostname)}
+	attempts, Err := r.Retry.do(ctx, func(ctx context.Context) error {
+		if err := r.Breaker.allow(); err != nil {
+			return err
+		}
+		err := postForm(ctx, client, "recaptcha", r.ReCAPTCHALink, formValues, &result)
+		r.Breaker.record(err)
+		return err
+	})
+	Result.Attempts = attempts
+	if Err != nil {
 		return
 	}
-
-	if options.ApkPackageName != "" && options.ApkPackageName != result.ApkPackageName {
-		Err = &Error{msg: fmt.Sprintf("invalid response ApkPackageName '%s', while expecting '%s'", result.ApkPackageName, options.ApkPackageName)}
-		return
+	Result = VerifyResult{
+		Success:        result.Success,
+		ChallengeTS:    result.ChallengeTS,
+		Hostname:       result.Hostname,
+		ApkPackageName: result.ApkPackageName,
+		Action:         result.Action,
+		Score:          result.Score,
+		ErrorCodes:     result.ErrorCodes,
+		Attempts:       attempts,
 	}
-
-	if options.ResponseTime != 0 {
-		duration := r.horloge.Since(result.ChallengeTS)
-		if options.ResponseTime < duration {
-			Err = &Error{msg: fmt.Sprintf("time spent in resolving challenge '%fs', while expecting maximum '%fs'", duration.Seconds(), options.ResponseTime.Seconds())}
-			return
-		}
+	threshold := options.Threshold
+	options = r.applyPolicy(&Result, options)
+	if r.Version != V3 {
+		options.Action = ""
 	}
-	if r.Version == V3 {
-		if options.Action != "" && options.Action != result.Action {
-			Err = &Error{msg: fmt.Sprintf("invalid response action '%s', while expecting '%s'", result.Action, options.Action)}
-			return
-		}
-		if options.Threshold != 0 && options.Threshold > result.Score {
-			Err = &Error{msg: fmt.Sprintf("received score '%f', while expecting minimum '%f'", result.Score, options.Threshold)}
-			return
-		}
-		if options.Threshold == 0 && DefaultThreshold > result.Score {
-			Err = &Error{msg: fmt.Sprintf("received score '%f', while expecting minimum '%f'", result.Score, DefaultThreshold)}
-			return
-		}
+	options.CData = ""
+	banded := r.Version == V3 && Result.Policy != nil && len(Result.Policy.Bands) > 0
+	// bands replace the policy threshold, a threshold set by the caller still applies
+	Err = checkResponse(&Result, options, r.horloge, r.Version == V3 && (!banded || threshold != 0))
+	if Err == nil && banded {
+		Err = applyBands(&Result)
 	}
+	Err = r.shadow(&Result, Err)
 	return
 }
@@ -1,11 +1,11 @@
 package recaptcha
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -33,7 +33,7 @@ func (s *ReCaptchaSuite) TestNewReCAPTCHA(c *C) {
 type mockInvalidClient struct{}
 type mockUnavailableClient struct{}
 
-func (*mockInvalidClient) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockInvalidClient) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -42,7 +42,7 @@ func (*mockInvalidClient) PostForm(url string, formValues url.Values) (resp *htt
 	return
 }
 
-func (*mockUnavailableClient) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockUnavailableClient) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "Not Found",
 		StatusCode: 404,
@@ -64,7 +64,7 @@ func (mockErrorReadCloser) Close() (err error) {
 
 type mockInvalidReaderClient struct{}
 
-func (*mockInvalidReaderClient) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockInvalidReaderClient) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -79,7 +79,7 @@ func (s *ReCaptchaSuite) TestConfirm(c *C) {
 	}
 	body := reCHAPTCHARequest{Secret: "", Response: ""}
 
-	err := captcha.confirm(body, VerifyOption{})
+	_, err := captcha.confirm(context.Background(), body, VerifyOption{})
 	c.Assert(err, NotNil)
 	recaptchaErr, ok := err.(*Error)
 	c.Check(ok, Equals, true)
@@ -87,7 +87,7 @@ func (s *ReCaptchaSuite) TestConfirm(c *C) {
 	c.Check(err, ErrorMatches, "invalid response body json:.*")
 
 	captcha.client = &mockUnavailableClient{}
-	err = captcha.confirm(body, VerifyOption{})
+	_, err = captcha.confirm(context.Background(), body, VerifyOption{})
 	c.Assert(err, NotNil)
 	recaptchaErr, ok = err.(*Error)
 	c.Check(ok, Equals, true)
@@ -95,7 +95,7 @@ func (s *ReCaptchaSuite) TestConfirm(c *C) {
 	c.Check(err, ErrorMatches, "error posting to recaptcha endpoint:.*")
 
 	captcha.client = &mockInvalidReaderClient{}
-	err = captcha.confirm(body, VerifyOption{})
+	_, err = captcha.confirm(context.Background(), body, VerifyOption{})
 	c.Assert(err, NotNil)
 	recaptchaErr, ok = err.(*Error)
 	c.Check(ok, Equals, true)
@@ -106,7 +106,7 @@ func (s *ReCaptchaSuite) TestConfirm(c *C) {
 
 type mockInvalidSolutionClient struct{}
 
-func (*mockInvalidSolutionClient) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockInvalidSolutionClient) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -136,7 +136,7 @@ func (s *ReCaptchaSuite) TestVerifyInvalidSolutionNoRemoteIp(c *C) {
 type mockSuccessClientNoOptions struct{}
 type mockFailedClientNoOptions struct{}
 
-func (*mockSuccessClientNoOptions) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockSuccessClientNoOptions) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -150,7 +150,7 @@ func (*mockSuccessClientNoOptions) PostForm(url string, formValues url.Values) (
 	`))
 	return
 }
-func (*mockFailedClientNoOptions) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockFailedClientNoOptions) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -185,7 +185,7 @@ func (s *ReCaptchaSuite) TestVerifyWithoutOptions(c *C) {
 type mockSuccessClientWithRemoteIPOption struct{}
 type mockFailClientWithRemoteIPOption struct{}
 
-func (*mockSuccessClientWithRemoteIPOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockSuccessClientWithRemoteIPOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -199,7 +199,7 @@ func (*mockSuccessClientWithRemoteIPOption) PostForm(url string, formValues url.
 	`))
 	return
 }
-func (*mockFailClientWithRemoteIPOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockFailClientWithRemoteIPOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -232,7 +232,7 @@ func (s *ReCaptchaSuite) TestVerifyWithRemoteIPOption(c *C) {
 type mockSuccessClientWithHostnameOption struct{}
 type mockFailClientWithHostnameOption struct{}
 
-func (*mockSuccessClientWithHostnameOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockSuccessClientWithHostnameOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -246,7 +246,7 @@ func (*mockSuccessClientWithHostnameOption) PostForm(url string, formValues url.
 	`))
 	return
 }
-func (*mockFailClientWithHostnameOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockFailClientWithHostnameOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -316,7 +316,7 @@ func (s *ReCaptchaSuite) TestVerifyWithResponseOption(c *C) {
 type mockSuccessClientWithApkPackageNameOption struct{}
 type mockFailClientWithApkPackageNameOption struct{}
 
-func (*mockSuccessClientWithApkPackageNameOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockSuccessClientWithApkPackageNameOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -330,7 +330,7 @@ func (*mockSuccessClientWithApkPackageNameOption) PostForm(url string, formValue
 	`))
 	return
 }
-func (*mockFailClientWithApkPackageNameOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockFailClientWithApkPackageNameOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -362,7 +362,7 @@ func (s *ReCaptchaSuite) TestVerifyWithApkPackageNameOption(c *C) {
 type mockV3SuccessClientWithActionOption struct{}
 type mockV3FailClientWithActionOption struct{}
 
-func (*mockV3SuccessClientWithActionOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockV3SuccessClientWithActionOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -377,7 +377,7 @@ func (*mockV3SuccessClientWithActionOption) PostForm(url string, formValues url.
 	`))
 	return
 }
-func (*mockV3FailClientWithActionOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockV3FailClientWithActionOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -415,7 +415,7 @@ func (s *ReCaptchaSuite) TestV3VerifyWithActionOption(c *C) {
 type mockV3SuccessClientWithThresholdOption struct{}
 type mockV3FailClientWithThresholdOption struct{}
 
-func (*mockV3SuccessClientWithThresholdOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockV3SuccessClientWithThresholdOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -429,7 +429,7 @@ func (*mockV3SuccessClientWithThresholdOption) PostForm(url string, formValues u
 	`))
 	return
 }
-func (*mockV3FailClientWithThresholdOption) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockV3FailClientWithThresholdOption) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -471,7 +471,7 @@ func (s *ReCaptchaSuite) TestV3VerifyWithThresholdOption(c *C) {
 
 type mockV2SuccessClientWithV3IgnoreOptions struct{}
 
-func (*mockV2SuccessClientWithV3IgnoreOptions) PostForm(url string, formValues url.Values) (resp *http.Response, err error) {
+func (*mockV2SuccessClientWithV3IgnoreOptions) Do(req *http.Request) (resp *http.Response, err error) {
 	resp = &http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
@@ -497,3 +497,108 @@ func (s *ReCaptchaSuite) TestRealClock(c *C) {
 	clock := &realClock{}
 	c.Check(clock.Since(time.Now()), FitsTypeOf, time.Duration(0))
 }
+
+type mockContextClient struct {
+	request *http.Request
+}
+
+func (m *mockContextClient) Do(req *http.Request) (resp *http.Response, err error) {
+	m.request = req
+	if err = req.Context().Err(); err != nil {
+		return
+	}
+	resp = &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(`
+	{
+		"success": true,
+		"challenge_ts": "2018-03-06T03:41:29+00:00",
+		"hostname": "test.com"
+	}
+	`))
+	return
+}
+
+func (s *ReCaptchaSuite) TestVerifyContext(c *C) {
+	client := &mockContextClient{}
+	captcha := ReCAPTCHA{
+		client:        client,
+		Secret:        "my secret",
+		ReCAPTCHALink: reCAPTCHALink,
+	}
+
+	err := captcha.VerifyWithOptionsContext(context.Background(), "mycode", VerifyOption{RemoteIP: "123.123.123.123"})
+	c.Assert(err, IsNil)
+	c.Check(client.request.Method, Equals, http.MethodPost)
+	c.Check(client.request.URL.String(), Equals, reCAPTCHALink)
+	c.Check(client.request.Header.Get("Content-Type"), Equals, "application/x-www-form-urlencoded")
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("secret"), Equals, "my secret")
+	c.Check(client.request.PostForm.Get("response"), Equals, "mycode")
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "123.123.123.123")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = captcha.VerifyContext(ctx, "mycode")
+	c.Assert(err, NotNil)
+	recaptchaErr, ok := err.(*Error)
+	c.Check(ok, Equals, true)
+	c.Check(recaptchaErr.RequestError, Equals, true)
+	c.Check(err, ErrorMatches, "error posting to recaptcha endpoint: 'context canceled'")
+}
+
+type mockV3FullResponseClient struct{}
+
+func (*mockV3FullResponseClient) Do(req *http.Request) (resp *http.Response, err error) {
+	resp = &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(`
+	{
+		"success": true,
+		"challenge_ts": "2018-03-06T03:41:29+00:00",
+		"hostname": "test2.com",
+		"action": "homepage",
+		"score": 0.3
+	}
+	`))
+	return
+}
+
+func (s *ReCaptchaSuite) TestCheck(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockV3FullResponseClient{},
+		horloge: &mockClockWithinRespenseTime{},
+		Version: V3,
+	}
+
+	result, err := captcha.Check("mycode", VerifyOption{Action: "homepage", Threshold: 0.2})
+	c.Assert(err, IsNil)
+	c.Check(result.Success, Equals, true)
+	c.Check(result.Hostname, Equals, "test2.com")
+	c.Check(result.Action, Equals, "homepage")
+	c.Check(result.Score, Equals, float32(0.3))
+	c.Check(result.ChallengeTS.Equal(time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)), Equals, true)
+	c.Check(result.Passed(CheckAction), Equals, true)
+	c.Check(result.Passed(CheckThreshold), Equals, true)
+
+	result, err = captcha.Check("mycode", VerifyOption{Hostname: "test.com", ResponseTime: 5 * time.Second, Action: "homepage"})
+	c.Assert(err, NotNil)
+	c.Check(err, ErrorMatches, "invalid response hostname 'test2.com', while expecting 'test.com'")
+	c.Check(result.Checks, DeepEquals, []CheckResult{
+		{Name: CheckHostname, Passed: false, Expected: "test.com", Received: "test2.com"},
+		{Name: CheckResponseTime, Passed: true, Expected: "5s", Received: "1s"},
+		{Name: CheckAction, Passed: true, Expected: "homepage", Received: "homepage"},
+		{Name: CheckThreshold, Passed: false, Expected: "0.500000", Received: "0.300000"},
+	})
+	c.Check(result.Passed(CheckApkPackageName), Equals, false)
+
+	captcha.client = &mockFailedClientNoOptions{}
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Assert(err, NotNil)
+	c.Check(result.ErrorCodes, DeepEquals, []string{"invalid-input-response", "bad-request"})
+	c.Check(result.Checks, IsNil)
+}
@@ -0,0 +1,162 @@
+// Package recaptchatest provides a fake siteverify server to test code verifying challenge responses end to end.
+//
+// Point ReCAPTCHALink (or HCaptchaLink, TurnstileLink) at Server.URL and script the answer of each token:
+//
+//	server := recaptchatest.NewServer()
+//	defer server.Close()
+//	server.Script("human", recaptchatest.Reply{Success: true, Score: 0.9, Action: "login"})
+//	captcha, _ := recaptcha.NewReCAPTCHA("secret", recaptcha.V3, time.Second)
+//	captcha.ReCAPTCHALink = server.URL
+package recaptchatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Reply scripted answer of the fake server for a token
+type Reply struct {
+	Success        bool
+	Score          float32
+	Action         string
+	Hostname       string
+	ApkPackageName string
+	// CData is the customer data echoed back by Turnstile.
+	CData string
+	// ChallengeTS defaults to the time the reply is sent.
+	ChallengeTS time.Time
+	// ErrorCodes is left out of the reply when nil, a non nil empty slice sends an empty list as Turnstile does.
+	ErrorCodes []string
+	// Latency delays the reply, the request is still aborted as soon as the client gives up.
+	Latency time.Duration
+	// Status is the http status of the reply, 200 when zero.
+	Status int
+	// Body replaces the json answer when set, use it to send malformed bodies.
+	Body string
+}
+
+// Request siteverify request received by the fake server
+type Request struct {
+	Secret   string
+	Response string
+	RemoteIP string
+	// Form holds every posted value, including provider specific ones like `sitekey` or `idempotency_key`.
+	Form url.Values
+	Time time.Time
+}
+
+// Server fake siteverify server, unscripted tokens are answered with the `invalid-input-response` error code
+// and requests without token with `missing-input-response` like the real service does
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  map[string]Reply
+	requests []Request
+}
+
+// NewServer starts a fake siteverify server, call Close when done
+func NewServer() *Server {
+	s := &Server{replies: make(map[string]Reply)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Script sets the reply sent for token
+func (s *Server) Script(token string, reply Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[token] = reply
+}
+
+// Requests returns the requests received so far, in arrival order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the scripted replies and the received requests
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = make(map[string]Reply)
+	s.requests = nil
+}
+
+type response struct {
+	Success        bool      `json:"success"`
+	ChallengeTS    time.Time `json:"challenge_ts"`
+	Hostname       string    `json:"hostname,omitempty"`
+	ApkPackageName string    `json:"apk_package_name,omitempty"`
+	Action         string    `json:"action,omitempty"`
+	Score          float32   `json:"score,omitempty"`
+	CData          string    `json:"cdata,omitempty"`
+	ErrorCodes     *[]string `json:"error-codes,omitempty"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	request := Request{
+		Secret:   r.PostForm.Get("secret"),
+		Response: r.PostForm.Get("response"),
+		RemoteIP: r.PostForm.Get("remoteip"),
+		Form:     r.PostForm,
+		Time:     time.Now(),
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	reply, ok := s.replies[request.Response]
+	s.mu.Unlock()
+	if !ok {
+		reply = Reply{ErrorCodes: []string{"invalid-input-response"}}
+		if request.Response == "" {
+			reply = Reply{ErrorCodes: []string{"missing-input-response"}}
+		}
+	}
+
+	if reply.Latency > 0 {
+		select {
+		case <-time.After(reply.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if reply.Body != "" {
+		w.Write([]byte(reply.Body))
+		return
+	}
+	challengeTS := reply.ChallengeTS
+	if challengeTS.IsZero() {
+		challengeTS = time.Now()
+	}
+	answer := response{
+		Success:        reply.Success,
+		ChallengeTS:    challengeTS,
+		Hostname:       reply.Hostname,
+		ApkPackageName: reply.ApkPackageName,
+		Action:         reply.Action,
+		Score:          reply.Score,
+		CData:          reply.CData,
+	}
+	if reply.ErrorCodes != nil {
+		answer.ErrorCodes = &reply.ErrorCodes
+	}
+	json.NewEncoder(w).Encode(answer)
+}
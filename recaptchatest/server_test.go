@@ -0,0 +1,117 @@
+package recaptchatest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/ezzarghili/recaptcha-go.v4"
+	"gopkg.in/ezzarghili/recaptcha-go.v4/recaptchatest"
+)
+
+func TestPackage(t *testing.T) { TestingT(t) }
+
+type ServerSuite struct {
+	server  *recaptchatest.Server
+	captcha recaptcha.ReCAPTCHA
+}
+
+var _ = Suite(&ServerSuite{})
+
+func (s *ServerSuite) SetUpTest(c *C) {
+	s.server = recaptchatest.NewServer()
+	var err error
+	s.captcha, err = recaptcha.NewReCAPTCHA("my secret", recaptcha.V3, 5*time.Second)
+	c.Assert(err, IsNil)
+	s.captcha.ReCAPTCHALink = s.server.URL
+}
+
+func (s *ServerSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *ServerSuite) TestScriptedReplies(c *C) {
+	s.server.Script("human", recaptchatest.Reply{Success: true, Score: 0.9, Action: "login", Hostname: "test.com"})
+	s.server.Script("bot", recaptchatest.Reply{Success: true, Score: 0.1, Action: "login", Hostname: "test.com"})
+	s.server.Script("slow", recaptchatest.Reply{Success: true, Score: 0.9, ChallengeTS: time.Now().Add(-time.Hour)})
+
+	result, err := s.captcha.Check("human", recaptcha.VerifyOption{Action: "login", Hostname: "test.com", RemoteIP: "123.123.123.123"})
+	c.Assert(err, IsNil)
+	c.Check(result.Score, Equals, float32(0.9))
+
+	err = s.captcha.Verify("bot")
+	c.Check(errors.Is(err, recaptcha.ErrScoreBelowThreshold), Equals, true)
+
+	err = s.captcha.VerifyWithOptions("slow", recaptcha.VerifyOption{ResponseTime: time.Minute})
+	c.Check(errors.Is(err, recaptcha.ErrResponseTooSlow), Equals, true)
+
+	err = s.captcha.Verify("unknown")
+	c.Check(errors.Is(err, recaptcha.ErrRemoteErrorCodes), Equals, true)
+	c.Check(err.(*recaptcha.Error).ErrorCodes, DeepEquals, []string{"invalid-input-response"})
+
+	err = s.captcha.Verify("")
+	c.Check(err.(*recaptcha.Error).ErrorCodes, DeepEquals, []string{"missing-input-response"})
+
+	requests := s.server.Requests()
+	c.Assert(requests, HasLen, 5)
+	c.Check(requests[0].Secret, Equals, "my secret")
+	c.Check(requests[0].Response, Equals, "human")
+	c.Check(requests[0].RemoteIP, Equals, "123.123.123.123")
+	c.Check(requests[1].Response, Equals, "bot")
+
+	s.server.Reset()
+	c.Check(s.server.Requests(), HasLen, 0)
+	c.Check(errors.Is(s.captcha.Verify("human"), recaptcha.ErrRemoteErrorCodes), Equals, true)
+}
+
+func (s *ServerSuite) TestFailures(c *C) {
+	s.server.Script("malformed", recaptchatest.Reply{Body: "not json"})
+	s.server.Script("latency", recaptchatest.Reply{Success: true, Score: 0.9, Latency: time.Second})
+	s.server.Script("unavailable", recaptchatest.Reply{Status: http.StatusServiceUnavailable, Body: "unavailable"})
+
+	err := s.captcha.Verify("malformed")
+	c.Check(errors.Is(err, recaptcha.ErrDecode), Equals, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = s.captcha.VerifyContext(ctx, "latency")
+	c.Check(errors.Is(err, recaptcha.ErrTransport), Equals, true)
+	c.Check(errors.Is(err, context.DeadlineExceeded), Equals, true)
+
+	resp, err := http.PostForm(s.server.URL, url.Values{"response": {"unavailable"}})
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, http.StatusServiceUnavailable)
+
+	resp, err = http.Get(s.server.URL)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, http.StatusMethodNotAllowed)
+}
+
+func (s *ServerSuite) TestTurnstile(c *C) {
+	turnstile, err := recaptcha.NewTurnstile("my secret", 5*time.Second)
+	c.Assert(err, IsNil)
+	turnstile.TurnstileLink = s.server.URL
+	s.server.Script("human", recaptchatest.Reply{Success: true, Action: "login", Hostname: "test.com", CData: "session", ErrorCodes: []string{}})
+
+	resp, err := http.PostForm(s.server.URL, url.Values{"response": {"human"}})
+	c.Assert(err, IsNil)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Check(string(body), Matches, `(?s).*"cdata":"session","error-codes":\[\].*`)
+
+	result, err := turnstile.Check("human", recaptcha.VerifyOption{Action: "login", CData: "session"})
+	c.Assert(err, IsNil)
+	c.Check(result.CData, Equals, "session")
+	c.Check(result.ErrorCodes, HasLen, 0)
+
+	_, err = turnstile.Check("human", recaptcha.VerifyOption{CData: "other"})
+	c.Check(errors.Is(err, recaptcha.ErrCDataMismatch), Equals, true)
+}
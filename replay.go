@@ -0,0 +1,139 @@
+package recaptcha
+
+import (
+	"container/heap"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenLifetime how long a challenge response can be verified after it was issued
+const TokenLifetime = 2 * time.Minute
+
+// ReplayStore remembers the challenge responses already verified, keyed by their hash.
+// Implementations must be safe for concurrent use, a shared store (redis, memcached...) protects a whole fleet
+type ReplayStore interface {
+	// Seen records key until expiry and reports whether it was already recorded, atomically.
+	Seen(key string, expiry time.Time) (bool, error)
+	// Forget removes key, it is called when recaptcha could not be reached so the token can be verified again.
+	Forget(key string) error
+}
+
+// hashToken returns the hex encoded sha256 of a challenge response, safe to store and log
+func hashToken(challengeResponse string) string {
+	sum := sha256.Sum256([]byte(challengeResponse))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimToken fails if the challenge response was already claimed or the store is full. Other ReplayStore errors
+// are ignored, recaptcha itself still rejects duplicates with the `timeout-or-duplicate` error code
+func (r *ReCAPTCHA) claimToken(challengeResponse string) error {
+	if r.ReplayStore == nil || challengeResponse == "" {
+		return nil
+	}
+	seen, err := r.ReplayStore.Seen(hashToken(challengeResponse), time.Now().Add(TokenLifetime))
+	if errors.Is(err, ErrReplayStoreFull) {
+		return err
+	}
+	if err == nil && seen {
+		return &Error{msg: "challenge response already verified", Reason: ReasonReplayedToken}
+	}
+	return nil
+}
+
+func (r *ReCAPTCHA) releaseToken(challengeResponse string) {
+	if r.ReplayStore == nil || challengeResponse == "" {
+		return
+	}
+	r.ReplayStore.Forget(hashToken(challengeResponse))
+}
+
+type replayEntry struct {
+	key    string
+	expiry time.Time
+	index  int
+}
+
+// replayHeap keys ordered by expiry, the next to expire first
+type replayHeap []*replayEntry
+
+func (h replayHeap) Len() int           { return len(h) }
+func (h replayHeap) Less(i, j int) bool { return h[i].expiry.Before(h[j].expiry) }
+func (h replayHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *replayHeap) Push(x interface{}) {
+	entry := x.(*replayEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *replayHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// MemoryReplayStore in memory ReplayStore for a single process, it holds at most capacity keys.
+// Keys are dropped once expired, when it is full of unexpired keys new challenge responses are refused
+// with ErrReplayStoreFull rather than forgetting keys that could then be replayed
+type MemoryReplayStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*replayEntry
+	expiries replayHeap
+	now      func() time.Time
+}
+
+// NewMemoryReplayStore returns an empty MemoryReplayStore holding at most capacity keys, unbounded when zero
+func NewMemoryReplayStore(capacity int) *MemoryReplayStore {
+	return &MemoryReplayStore{
+		capacity: capacity,
+		entries:  make(map[string]*replayEntry),
+		now:      time.Now,
+	}
+}
+
+// Seen implements ReplayStore
+func (m *MemoryReplayStore) Seen(key string, expiry time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for len(m.expiries) > 0 && !m.expiries[0].expiry.After(now) {
+		delete(m.entries, heap.Pop(&m.expiries).(*replayEntry).key)
+	}
+	if _, ok := m.entries[key]; ok {
+		return true, nil
+	}
+	if m.capacity > 0 && len(m.expiries) >= m.capacity {
+		return false, &Error{msg: "replay store full, challenge response refused", Reason: ReasonReplayStoreFull}
+	}
+	entry := &replayEntry{key: key, expiry: expiry}
+	heap.Push(&m.expiries, entry)
+	m.entries[key] = entry
+	return false, nil
+}
+
+// Forget implements ReplayStore
+func (m *MemoryReplayStore) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.entries[key]; ok {
+		heap.Remove(&m.expiries, entry.index)
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of keys held
+func (m *MemoryReplayStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.expiries)
+}
@@ -0,0 +1,119 @@
+package recaptcha
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ReplaySuite struct{}
+
+var _ = Suite(&ReplaySuite{})
+
+func (s *ReplaySuite) TestMemoryReplayStore(c *C) {
+	now := time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	store := NewMemoryReplayStore(2)
+	store.now = func() time.Time { return now }
+
+	seen, err := store.Seen("a", now.Add(TokenLifetime))
+	c.Assert(err, IsNil)
+	c.Check(seen, Equals, false)
+	seen, _ = store.Seen("a", now.Add(TokenLifetime))
+	c.Check(seen, Equals, true)
+
+	// full of unexpired keys, new keys are refused instead of evicting a key that could be replayed
+	seen, err = store.Seen("b", now.Add(time.Minute))
+	c.Check(seen, Equals, false)
+	c.Check(err, IsNil)
+	seen, err = store.Seen("c", now.Add(TokenLifetime))
+	c.Check(seen, Equals, false)
+	c.Check(errors.Is(err, ErrReplayStoreFull), Equals, true)
+	c.Check(store.Len(), Equals, 2)
+	seen, _ = store.Seen("a", now.Add(TokenLifetime))
+	c.Check(seen, Equals, true)
+
+	// keys expire by their own expiry, "b" added last expires first
+	now = now.Add(time.Minute)
+	seen, err = store.Seen("c", now.Add(TokenLifetime))
+	c.Check(seen, Equals, false)
+	c.Check(err, IsNil)
+	seen, _ = store.Seen("a", now.Add(TokenLifetime))
+	c.Check(seen, Equals, true)
+	c.Check(store.Len(), Equals, 2)
+
+	now = now.Add(TokenLifetime)
+	c.Check(store.Len(), Equals, 2)
+	seen, _ = store.Seen("c", now.Add(TokenLifetime))
+	c.Check(seen, Equals, false)
+	c.Check(store.Len(), Equals, 1)
+
+	c.Assert(store.Forget("c"), IsNil)
+	c.Check(store.Len(), Equals, 0)
+	c.Check(store.Forget("missing"), IsNil)
+}
+
+func (s *ReplaySuite) TestReplayGuard(c *C) {
+	store := NewMemoryReplayStore(100)
+	captcha := ReCAPTCHA{
+		client:      &mockSuccessClientNoOptions{},
+		ReplayStore: store,
+	}
+
+	c.Assert(captcha.Verify("mycode"), IsNil)
+	err := captcha.Verify("mycode")
+	c.Assert(err, NotNil)
+	c.Check(errors.Is(err, ErrReplayedToken), Equals, true)
+	c.Check(err.(*Error).RequestError, Equals, false)
+	c.Check(err, ErrorMatches, "challenge response already verified")
+
+	// tokens recaptcha never saw can be verified again
+	captcha.client = &mockUnavailableClient{}
+	c.Check(errors.Is(captcha.Verify("othercode"), ErrTransport), Equals, true)
+	captcha.client = &mockSuccessClientNoOptions{}
+	c.Check(captcha.Verify("othercode"), IsNil)
+	c.Check(store.Len(), Equals, 2)
+
+	_, ok := store.entries[hashToken("mycode")]
+	c.Check(ok, Equals, true)
+	_, ok = store.entries["mycode"]
+	c.Check(ok, Equals, false)
+}
+
+func (s *ReplaySuite) TestReplayGuardFull(c *C) {
+	captcha := ReCAPTCHA{
+		client:      &mockSuccessClientNoOptions{},
+		ReplayStore: NewMemoryReplayStore(2),
+	}
+	c.Assert(captcha.Verify("first"), IsNil)
+	c.Assert(captcha.Verify("second"), IsNil)
+
+	err := captcha.Verify("third")
+	c.Check(errors.Is(err, ErrReplayStoreFull), Equals, true)
+	c.Check(err.(*Error).RequestError, Equals, false)
+	c.Check(errors.Is(captcha.Verify("first"), ErrReplayedToken), Equals, true)
+}
+
+func (s *ReplaySuite) TestConcurrentSubmissions(c *C) {
+	captcha := ReCAPTCHA{
+		client:      &mockSuccessClientNoOptions{},
+		ReplayStore: NewMemoryReplayStore(100),
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if captcha.Verify("mycode") == nil {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	c.Check(passed, Equals, 1)
+}
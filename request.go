@@ -0,0 +1,99 @@
+package recaptcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// DefaultTokenField name of the form field the recaptcha widget stores the challenge response in
+const DefaultTokenField = "g-recaptcha-response"
+
+// TokenSource extracts the challenge response from an incoming request, returns "" when there is none
+type TokenSource func(req *http.Request) string
+
+// FormToken returns a TokenSource reading the challenge response from the given form field
+func FormToken(field string) TokenSource {
+	return func(req *http.Request) string {
+		return req.FormValue(field)
+	}
+}
+
+// HeaderToken returns a TokenSource reading the challenge response from the given header
+func HeaderToken(header string) TokenSource {
+	return func(req *http.Request) string {
+		return req.Header.Get(header)
+	}
+}
+
+// MaxJSONTokenBytes is the largest json body JSONToken reads the challenge response from,
+// larger bodies are left unparsed and carry no challenge response, use HeaderToken for large payloads
+const MaxJSONTokenBytes = 1 << 20
+
+// JSONToken returns a TokenSource reading the challenge response from the given top level field of a json body
+// of at most MaxJSONTokenBytes, the body is restored afterwards so the handler can still decode it
+func JSONToken(field string) TokenSource {
+	return func(req *http.Request) string {
+		if req.Body == nil {
+			return ""
+		}
+		original := req.Body
+		body, err := ioutil.ReadAll(io.LimitReader(original, MaxJSONTokenBytes+1))
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		if err != nil || len(body) > MaxJSONTokenBytes {
+			return ""
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &fields) != nil {
+			return ""
+		}
+		var token string
+		if json.Unmarshal(fields[field], &token) != nil {
+			return ""
+		}
+		return token
+	}
+}
+
+// VerifyRequest is like VerifyWithOptions but reads the challenge response from req using r.TokenSource,
+// options.RemoteIP is resolved from req by r.ClientIP when empty and the request to recaptcha is bound to req's context
+func (r *ReCAPTCHA) VerifyRequest(req *http.Request, options VerifyOption) error {
+	_, err := r.CheckRequest(req, options)
+	return err
+}
+
+// CheckRequest is like VerifyRequest but also returns the VerifyResult, see Check
+func (r *ReCAPTCHA) CheckRequest(req *http.Request, options VerifyOption) (VerifyResult, error) {
+	return r.checkRequest(req, nil, options)
+}
+
+func (r *ReCAPTCHA) checkRequest(req *http.Request, source TokenSource, options VerifyOption) (VerifyResult, error) {
+	if source == nil {
+		source = r.TokenSource
+	}
+	if source == nil {
+		source = FormToken(DefaultTokenField)
+	}
+	if options.RemoteIP == "" {
+		options.RemoteIP = r.ClientIP.Resolve(req)
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = req.UserAgent()
+	}
+	return r.CheckContext(req.Context(), source(req), options)
+}
+
+// remoteIP returns the host part of req.RemoteAddr
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
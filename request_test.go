@@ -0,0 +1,69 @@
+package recaptcha
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RequestSuite struct{}
+
+var _ = Suite(&RequestSuite{})
+
+func (s *RequestSuite) TestTokenSources(c *C) {
+	req := formRequest(url.Values{DefaultTokenField: {"form-code"}})
+	c.Check(FormToken(DefaultTokenField)(req), Equals, "form-code")
+	c.Check(FormToken("other")(req), Equals, "")
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-Recaptcha-Token", "header-code")
+	c.Check(HeaderToken("X-Recaptcha-Token")(req), Equals, "header-code")
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(`{"name": "gopher", "token": "json-code"}`))
+	c.Check(JSONToken("token")(req), Equals, "json-code")
+	c.Check(JSONToken("name")(req), Equals, "gopher")
+	c.Check(JSONToken("missing")(req), Equals, "")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Equals, `{"name": "gopher", "token": "json-code"}`)
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(`not json`))
+	c.Check(JSONToken("token")(req), Equals, "")
+
+	// large bodies are not parsed but reach the handler whole
+	large := `{"token": "json-code", "padding": "` + strings.Repeat("x", MaxJSONTokenBytes) + `"}`
+	req = httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(large))
+	c.Check(JSONToken("token")(req), Equals, "")
+	body, err = ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body) == large, Equals, true)
+}
+
+func (s *RequestSuite) TestVerifyRequest(c *C) {
+	client := &mockContextClient{}
+	captcha := ReCAPTCHA{
+		client: client,
+	}
+
+	req := formRequest(url.Values{DefaultTokenField: {"mycode"}})
+	req.RemoteAddr = "123.123.123.123:4242"
+	err := captcha.VerifyRequest(req, VerifyOption{Hostname: "test.com"})
+	c.Assert(err, IsNil)
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("response"), Equals, "mycode")
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "123.123.123.123")
+
+	captcha.TokenSource = HeaderToken("X-Recaptcha-Token")
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Recaptcha-Token", "header-code")
+	result, err := captcha.CheckRequest(req, VerifyOption{RemoteIP: "10.0.0.1"})
+	c.Assert(err, IsNil)
+	c.Check(result.Hostname, Equals, "test.com")
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("response"), Equals, "header-code")
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "10.0.0.1")
+}
@@ -0,0 +1,93 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy how requests to recaptcha are retried on transient failures,
+// definitive answers like `invalid-input-response` are never retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of requests sent, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, 100ms when zero.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts, 2s when zero.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after each attempt, 2 when zero.
+	Multiplier float64
+	// Jitter randomly shortens each wait by up to this fraction, between 0 and 1.
+	Jitter float64
+	// Deadline bounds the time spent in all attempts and waits, unbounded when zero.
+	Deadline time.Duration
+	// Retryable tells which errors are retried, IsRetryable when nil.
+	Retryable func(err error) bool
+}
+
+// IsRetryable returns true for transport failures and 5xx or 429 answers of the verification service
+func IsRetryable(err error) bool {
+	var recaptchaErr *Error
+	if !errors.As(err, &recaptchaErr) || recaptchaErr.Reason != ReasonTransport {
+		return false
+	}
+	switch code := recaptchaErr.StatusCode; {
+	case code == 0:
+		return !errors.Is(err, context.Canceled)
+	case code == http.StatusTooManyRequests, code >= 500:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait after the given attempt, starting at 1
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial, max, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 2 * time.Second
+	}
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	wait := time.Duration(math.Min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(max)))
+	if p.Jitter > 0 {
+		wait -= time.Duration(rand.Float64() * math.Min(p.Jitter, 1) * float64(wait))
+	}
+	return wait
+}
+
+// do calls attempt until it succeeds, fails with a non retryable error, the attempts are exhausted or ctx is done.
+// It returns the number of attempts made and the last error, a nil policy makes a single attempt
+func (p *RetryPolicy) do(ctx context.Context, attempt func(ctx context.Context) error) (int, error) {
+	if p == nil || p.MaxAttempts <= 1 {
+		return 1, attempt(ctx)
+	}
+	if p.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Deadline)
+		defer cancel()
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	for attempts := 1; ; attempts++ {
+		err := attempt(ctx)
+		if err == nil || attempts >= p.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return attempts, err
+		}
+		timer := time.NewTimer(p.backoff(attempts))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		}
+	}
+}
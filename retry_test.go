@@ -0,0 +1,127 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type RetrySuite struct{}
+
+var _ = Suite(&RetrySuite{})
+
+// mockSequenceClient answers with the given statuses in turn, 0 meaning a transport error
+type mockSequenceClient struct {
+	statuses []int
+	body     string
+	calls    int
+}
+
+func (m *mockSequenceClient) Do(req *http.Request) (resp *http.Response, err error) {
+	status := m.statuses[m.calls%len(m.statuses)]
+	m.calls++
+	if status == 0 {
+		return nil, fmt.Errorf("connection reset by peer")
+	}
+	resp = &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(m.body))
+	return
+}
+
+func (s *RetrySuite) TestIsRetryable(c *C) {
+	c.Check(IsRetryable(&Error{Reason: ReasonTransport, err: errors.New("reset")}), Equals, true)
+	c.Check(IsRetryable(&Error{Reason: ReasonTransport, StatusCode: 503}), Equals, true)
+	c.Check(IsRetryable(&Error{Reason: ReasonTransport, StatusCode: 429}), Equals, true)
+	c.Check(IsRetryable(&Error{Reason: ReasonTransport, StatusCode: 400}), Equals, false)
+	c.Check(IsRetryable(&Error{Reason: ReasonTransport, err: context.Canceled}), Equals, false)
+	c.Check(IsRetryable(&Error{Reason: ReasonRemoteErrorCodes, ErrorCodes: []string{"invalid-input-response"}}), Equals, false)
+	c.Check(IsRetryable(&Error{Reason: ReasonDecode}), Equals, false)
+	c.Check(IsRetryable(errors.New("other")), Equals, false)
+	c.Check(IsRetryable(nil), Equals, false)
+}
+
+func (s *RetrySuite) TestBackoff(c *C) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	c.Check(policy.backoff(1), Equals, 100*time.Millisecond)
+	c.Check(policy.backoff(2), Equals, 300*time.Millisecond)
+	c.Check(policy.backoff(3), Equals, 900*time.Millisecond)
+	c.Check(policy.backoff(4), Equals, time.Second)
+
+	c.Check((&RetryPolicy{}).backoff(2), Equals, 200*time.Millisecond)
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		wait := policy.backoff(2)
+		c.Assert(wait <= 300*time.Millisecond && wait >= 150*time.Millisecond, Equals, true, Commentf("%s", wait))
+	}
+}
+
+func (s *RetrySuite) TestRetry(c *C) {
+	client := &mockSequenceClient{
+		statuses: []int{0, 503, 200},
+		body:     `{"success": true, "challenge_ts": "2018-03-06T03:41:29+00:00", "hostname": "test.com"}`,
+	}
+	captcha := ReCAPTCHA{
+		client: client,
+		Retry:  &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	}
+	result, err := captcha.Check("mycode", VerifyOption{})
+	c.Assert(err, IsNil)
+	c.Check(client.calls, Equals, 3)
+	c.Check(result.Attempts, Equals, 3)
+
+	// attempts are exhausted
+	client.statuses, client.calls = []int{503}, 0
+	captcha.Retry.MaxAttempts = 2
+	result, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(client.calls, Equals, 2)
+	c.Check(result.Attempts, Equals, 2)
+	c.Check(err.(*Error).StatusCode, Equals, 503)
+	c.Check(err, ErrorMatches, "recaptcha endpoint answered with status 503")
+
+	// definitive answers are not retried
+	client.statuses, client.calls, client.body = []int{200}, 0, `{"success": false, "error-codes": ["invalid-input-response"]}`
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+	c.Check(client.calls, Equals, 1)
+
+	client.statuses, client.calls = []int{400}, 0
+	err = captcha.Verify("mycode")
+	c.Check(err.(*Error).StatusCode, Equals, 400)
+	c.Check(client.calls, Equals, 1)
+
+	// custom classification
+	client.statuses, client.calls = []int{0}, 0
+	captcha.Retry.Retryable = func(err error) bool { return false }
+	captcha.Verify("mycode")
+	c.Check(client.calls, Equals, 1)
+}
+
+func (s *RetrySuite) TestRetryDeadline(c *C) {
+	client := &mockSequenceClient{statuses: []int{0}}
+	captcha := ReCAPTCHA{
+		client: client,
+		Retry:  &RetryPolicy{MaxAttempts: 100, InitialBackoff: 20 * time.Millisecond, Multiplier: 1, Deadline: 50 * time.Millisecond},
+	}
+	start := time.Now()
+	err := captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrTransport), Equals, true)
+	c.Check(time.Since(start) < time.Second, Equals, true)
+	c.Check(client.calls >= 2 && client.calls <= 4, Equals, true, Commentf("%d calls", client.calls))
+
+	client.calls = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	captcha.client = &mockContextClient{}
+	err = captcha.VerifyContext(ctx, "mycode")
+	c.Check(errors.Is(err, context.Canceled), Equals, true)
+}
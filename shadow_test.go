@@ -0,0 +1,94 @@
+package recaptcha
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ShadowSuite struct{}
+
+var _ = Suite(&ShadowSuite{})
+
+func (s *ShadowSuite) TestShadow(c *C) {
+	sink := &mockAuditSink{}
+	metrics := NewPrometheusObserver()
+	captcha := ReCAPTCHA{
+		client:   &mockBodyClient{body: v3Body("0.3")},
+		Version:  V3,
+		Shadow:   true,
+		Observer: metrics,
+		Audit:    sink,
+	}
+	result, err := captcha.Check("mycode", VerifyOption{Threshold: 0.5, Hostname: "test.com"})
+	c.Assert(err, IsNil)
+	c.Check(result.Decision, Equals, DecisionAllow)
+	c.Check(errors.Is(result.ShadowError, ErrHostnameMismatch), Equals, true)
+	c.Check(result.Passed(CheckHostname), Equals, false)
+	c.Check(result.Passed(CheckThreshold), Equals, false)
+	c.Check(result.Checks, HasLen, 2)
+
+	c.Assert(sink.records, HasLen, 1)
+	c.Check(sink.records[0].Reason, Equals, "none")
+	c.Check(sink.records[0].ShadowReason, Equals, "hostname_mismatch")
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	c.Check(strings.Contains(rec.Body.String(), `recaptcha_shadow_failures_total{version="v3",action="comment",reason="hostname_mismatch"} 1`), Equals, true)
+
+	result, err = captcha.Check("mycode", VerifyOption{Threshold: 0.2})
+	c.Check(err, IsNil)
+	c.Check(result.ShadowError, IsNil)
+}
+
+func (s *ShadowSuite) TestShadowNoHumanPass(c *C) {
+	pass, err := NewHumanPass(stepUpKey)
+	c.Assert(err, IsNil)
+	captcha := ReCAPTCHA{client: &mockBodyClient{body: v3Body("0.3")}, Version: V3, Shadow: true}
+	handler := captcha.Middleware(WithVerifyOption(VerifyOption{Action: "comment"}), WithHumanPass(pass))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		result, _ := ResultFromContext(req.Context())
+		c.Check(result.ShadowError, NotNil)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{DefaultTokenField: {"mycode"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	c.Check(rec.Result().Cookies(), HasLen, 0)
+}
+
+func (s *ShadowSuite) TestShadowKeepsUnsolved(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockBodyClient{body: `{"success": false, "error-codes": ["invalid-input-response"]}`},
+		Version: V3,
+		Shadow:  true,
+	}
+	_, err := captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+
+	captcha.client = &mockBodyClient{body: `{"success": false}`}
+	_, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrInvalidSolution), Equals, true)
+}
+
+func (s *ShadowSuite) TestShadowPolicy(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockBodyClient{body: v3Body("0.3")},
+		Version: V3,
+		Policies: PolicyTable{
+			{Action: "comment", Shadow: true, Bands: []ScoreBand{{MinScore: 0.7, Decision: DecisionAllow}, {MinScore: 0.2, Decision: DecisionChallenge}}},
+			{Action: "*", Threshold: 0.5},
+		},
+	}
+	result, err := captcha.Check("mycode", VerifyOption{})
+	c.Check(err, IsNil)
+	c.Check(result.Decision, Equals, DecisionAllow)
+	c.Check(errors.Is(result.ShadowError, ErrChallengeRequired), Equals, true)
+
+	captcha.client = &mockBodyClient{body: `{"success": true, "action": "login", "score": 0.3}`}
+	_, err = captcha.Check("mycode", VerifyOption{})
+	c.Check(errors.Is(err, ErrScoreBelowThreshold), Equals, true)
+}
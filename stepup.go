@@ -0,0 +1,333 @@
+package recaptcha
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// StepUpStateField name of the form field carrying the step-up state on the challenge page
+const StepUpStateField = "recaptcha-step-up"
+
+// ErrStepUpState the step-up state is missing, tampered with, expired or used from another client or URL
+var ErrStepUpState = errors.New("recaptcha: invalid step-up state")
+
+// DefaultStepUpTemplate challenge page rendering the V2 checkbox, executed with a StepUpPage
+var DefaultStepUpTemplate = template.Must(template.New("step-up").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Verification required</title>
+<script src="https://www.google.com/recaptcha/api.js" async defer></script>
+</head>
+<body>
+<form method="POST" action="{{.Action}}">
+<div class="g-recaptcha" data-sitekey="{{.SiteKey}}"></div>
+<input type="hidden" name="{{.StateField}}" value="{{.State}}">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// StepUpPage data the challenge page template is executed with
+type StepUpPage struct {
+	// Action is the URL the form must be posted to.
+	Action     string
+	SiteKey    string
+	StateField string
+	// State must be posted back in StateField along with the V2 challenge response.
+	State string
+}
+
+// StepUp shows a V2 checkbox challenge instead of rejecting requests whose V3 score band asks for a challenge.
+// The original request is carried by the challenge page in an encrypted and signed short-lived state,
+// once the V2 challenge is solved it is replayed to the protected handler.
+// The state is bound to the client IP resolved by V3.ClientIP
+type StepUp struct {
+	V3 *ReCAPTCHA
+	V2 *ReCAPTCHA
+	// V2SiteKey is the site key rendering the checkbox widget.
+	V2SiteKey string
+	// V2Options are the options the V2 challenge response is verified with.
+	V2Options VerifyOption
+	// TTL is how long the challenge page can be submitted, 5 minutes when zero.
+	TTL time.Duration
+	// MaxBodyBytes bounds the size of the original request body carried by the challenge page, 64KB when zero.
+	// Larger requests to challenge are answered with 413. Requests let through are streamed unchanged,
+	// unless the token source parsed a body larger than MaxBodyBytes, the handler then only gets the parsed form.
+	MaxBodyBytes int64
+	// Template renders the challenge page, DefaultStepUpTemplate when nil.
+	Template *template.Template
+
+	aead cipher.AEAD
+	now  func() time.Time
+}
+
+// NewStepUp returns a StepUp challenging with v2 the requests v3 scores low, key is an AES key of 16, 24 or 32 bytes
+// encrypting the state, it must be kept secret and shared by every instance serving the challenge page
+func NewStepUp(v3 *ReCAPTCHA, v2 *ReCAPTCHA, v2SiteKey string, key []byte) (*StepUp, error) {
+	if v3 == nil || v3.Version != V3 || v2 == nil || v2.Version != V2 {
+		return nil, fmt.Errorf("step-up requires a V3 and a V2 recaptcha")
+	}
+	if v2SiteKey == "" {
+		return nil, fmt.Errorf("recaptcha V2 site key cannot be blank")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step-up key: %s", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step-up key: %s", err)
+	}
+	return &StepUp{V3: v3, V2: v2, V2SiteKey: v2SiteKey, aead: aead}, nil
+}
+
+type stepUpState struct {
+	Method      string `json:"m"`
+	URL         string `json:"u"`
+	ContentType string `json:"t,omitempty"`
+	Body        []byte `json:"b,omitempty"`
+	RemoteIP    string `json:"ip"`
+	Expiry      int64  `json:"e"`
+}
+
+type stepUpBodyKey struct{}
+
+// bodyRecorder keeps up to limit bytes of what is read from a request body, by token sources or form parsing,
+// so the body can be replayed in full to the handler or carried by the challenge page.
+// Past limit it stops recording and is marked overflowed, the bytes read so far are then lost to the handler
+// which is left with the parsed form, as without step-up
+type bodyRecorder struct {
+	body       io.ReadCloser
+	limit      int64
+	read       bytes.Buffer
+	overflowed bool
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if !b.overflowed {
+		if int64(b.read.Len()+n) > b.limit {
+			b.overflowed = true
+			b.read = bytes.Buffer{}
+		} else {
+			b.read.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+func (b *bodyRecorder) Close() error {
+	return b.body.Close()
+}
+
+// replay returns the body as sent, what was read followed by the rest of it, only the rest once overflowed
+func (b *bodyRecorder) replay() io.ReadCloser {
+	if b.overflowed {
+		return b.body
+	}
+	return readCloser{io.MultiReader(bytes.NewReader(b.read.Bytes()), b.body), b.body}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Middleware is like ReCAPTCHA.Middleware for the V3 instance, requests to challenge get the V2 challenge page
+// and the resubmitted page is verified with the V2 instance before the original request reaches the handler.
+// The reject handler set in opts is also used when the V2 verification or the state fails
+func (s *StepUp) Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := newMiddleware(s.V3, opts)
+	m.challenge = http.HandlerFunc(s.serveChallenge)
+	return func(next http.Handler) http.Handler {
+		protected := m.wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if recorder, ok := req.Context().Value(stepUpBodyKey{}).(*bodyRecorder); ok {
+				req.Body = recorder.replay()
+			}
+			next.ServeHTTP(w, req)
+		}))
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Body == nil {
+				req.Body = http.NoBody
+			}
+			recorder := &bodyRecorder{body: req.Body, limit: s.maxBodyBytes()}
+			if req.Method == http.MethodPost && isURLEncoded(req) {
+				// the challenge page is a small form, larger bodies cannot be resubmissions
+				original := req.Body
+				head, err := ioutil.ReadAll(io.LimitReader(original, recorder.limit+1))
+				recorder.body = readCloser{io.MultiReader(bytes.NewReader(head), original), original}
+				if err == nil && int64(len(head)) <= recorder.limit {
+					if values, err := url.ParseQuery(string(head)); err == nil && values.Get(StepUpStateField) != "" {
+						req.Body = ioutil.NopCloser(bytes.NewReader(head))
+						s.serveResubmission(w, req, next, m.reject)
+						return
+					}
+				}
+			}
+			req.Body = recorder
+			protected.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), stepUpBodyKey{}, recorder)))
+		})
+	}
+}
+
+// readBody reads the whole request body, up to MaxBodyBytes, including what token sources already read
+func (s *StepUp) readBody(req *http.Request) ([]byte, error) {
+	recorder, ok := req.Context().Value(stepUpBodyKey{}).(*bodyRecorder)
+	if !ok {
+		return nil, nil
+	}
+	if recorder.overflowed {
+		return nil, errStepUpBodyTooLarge
+	}
+	limit := s.maxBodyBytes()
+	body, err := ioutil.ReadAll(io.LimitReader(recorder.replay(), limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errStepUpBodyTooLarge
+	}
+	return body, nil
+}
+
+var errStepUpBodyTooLarge = errors.New("request body too large")
+
+func (s *StepUp) serveChallenge(w http.ResponseWriter, req *http.Request) {
+	body, err := s.readBody(req)
+	if err == errStepUpBodyTooLarge {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	state, err := s.seal(stepUpState{
+		Method:      req.Method,
+		URL:         req.URL.RequestURI(),
+		ContentType: req.Header.Get("Content-Type"),
+		Body:        body,
+		RemoteIP:    s.clientIP(req),
+		Expiry:      s.clock().Add(s.ttl()).Unix(),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	tmpl := s.Template
+	if tmpl == nil {
+		tmpl = DefaultStepUpTemplate
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	tmpl.Execute(w, StepUpPage{Action: req.URL.RequestURI(), SiteKey: s.V2SiteKey, StateField: StepUpStateField, State: state})
+}
+
+func (s *StepUp) serveResubmission(w http.ResponseWriter, req *http.Request, next http.Handler, reject RejectHandler) {
+	state, err := s.open(req.PostFormValue(StepUpStateField))
+	if err != nil || state.URL != req.URL.RequestURI() || state.RemoteIP != s.clientIP(req) {
+		reject(w, req, ErrStepUpState)
+		return
+	}
+	result, err := s.V2.checkRequest(req, FormToken(DefaultTokenField), s.V2Options)
+	if err != nil {
+		reject(w, req, err)
+		return
+	}
+	original := req.Clone(NewContext(req.Context(), result))
+	original.Method = state.Method
+	original.URL, err = url.ParseRequestURI(state.URL)
+	if err != nil {
+		reject(w, req, ErrStepUpState)
+		return
+	}
+	original.RequestURI = state.URL
+	original.Body = ioutil.NopCloser(bytes.NewReader(state.Body))
+	original.ContentLength = int64(len(state.Body))
+	original.Header.Del("Content-Type")
+	if state.ContentType != "" {
+		original.Header.Set("Content-Type", state.ContentType)
+	}
+	original.Form, original.PostForm, original.MultipartForm = nil, nil, nil
+	next.ServeHTTP(w, original)
+}
+
+// seal encrypts and authenticates state, the nonce is prepended to the ciphertext
+func (s *StepUp) seal(state stepUpState) (string, error) {
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, []byte(StepUpStateField))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a state sealed by seal and checks it did not expire
+func (s *StepUp) open(sealed string) (stepUpState, error) {
+	var state stepUpState
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return state, ErrStepUpState
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(StepUpStateField))
+	if err != nil || json.Unmarshal(plaintext, &state) != nil {
+		return state, ErrStepUpState
+	}
+	if s.clock().Unix() > state.Expiry {
+		return state, ErrStepUpState
+	}
+	return state, nil
+}
+
+func (s *StepUp) maxBodyBytes() int64 {
+	if s.MaxBodyBytes <= 0 {
+		return 64 << 10
+	}
+	return s.MaxBodyBytes
+}
+
+// isURLEncoded reports whether req carries a url encoded form, the only kind the challenge page posts
+func isURLEncoded(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// clientIP resolves the client IP the state is bound to with the V3 resolver, the one the challenge is issued with
+func (s *StepUp) clientIP(req *http.Request) string {
+	return s.V3.ClientIP.Resolve(req)
+}
+
+func (s *StepUp) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return 5 * time.Minute
+	}
+	return s.TTL
+}
+
+func (s *StepUp) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
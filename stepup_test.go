@@ -0,0 +1,270 @@
+package recaptcha
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type StepUpSuite struct {
+	v2Client *mockBodyClient
+	stepUp   *StepUp
+	now      time.Time
+}
+
+var _ = Suite(&StepUpSuite{})
+
+var stepUpKey = []byte("0123456789abcdef0123456789abcdef")
+
+func (s *StepUpSuite) SetUpTest(c *C) {
+	v3 := &ReCAPTCHA{
+		client:  &mockBodyClient{body: v3Body("0.3")},
+		Version: V3,
+		Policies: PolicyTable{{Action: "*", Bands: []ScoreBand{
+			{MinScore: 0.7, Decision: DecisionAllow},
+			{MinScore: 0.2, Decision: DecisionChallenge},
+		}}},
+	}
+	s.v2Client = &mockBodyClient{body: `{"success": true, "challenge_ts": "2018-03-06T03:41:29+00:00", "hostname": "test.com"}`}
+	v2 := &ReCAPTCHA{client: s.v2Client, Version: V2}
+	var err error
+	s.stepUp, err = NewStepUp(v3, v2, "my site key", stepUpKey)
+	c.Assert(err, IsNil)
+	s.now = time.Date(2018, 3, 6, 3, 41, 29, 0, time.UTC)
+	s.stepUp.now = func() time.Time { return s.now }
+}
+
+func (s *StepUpSuite) TestNewStepUp(c *C) {
+	v3, v2 := &ReCAPTCHA{Version: V3}, &ReCAPTCHA{Version: V2}
+	_, err := NewStepUp(v2, v3, "my site key", stepUpKey)
+	c.Check(err, ErrorMatches, "step-up requires a V3 and a V2 recaptcha")
+	_, err = NewStepUp(v3, v2, "", stepUpKey)
+	c.Check(err, ErrorMatches, "recaptcha V2 site key cannot be blank")
+	_, err = NewStepUp(v3, v2, "my site key", []byte("short"))
+	c.Check(err, ErrorMatches, "invalid step-up key.*")
+}
+
+var stateRe = regexp.MustCompile(`name="` + StepUpStateField + `" value="([^"]+)"`)
+
+func (s *StepUpSuite) challenge(c *C, handler http.Handler) string {
+	req := httptest.NewRequest(http.MethodPost, "/comments?page=2", strings.NewReader(`{"comment": "hello", "g-recaptcha-response": "v3code"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "123.123.123.123:4242"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Assert(rec.Code, Equals, http.StatusOK)
+	page := rec.Body.String()
+	c.Check(strings.Contains(page, `data-sitekey="my site key"`), Equals, true)
+	c.Check(strings.Contains(page, `action="/comments?page=2"`), Equals, true)
+	match := stateRe.FindStringSubmatch(page)
+	c.Assert(match, HasLen, 2)
+	return match[1]
+}
+
+func resubmit(state string, remoteAddr string) *http.Request {
+	req := formRequest(url.Values{StepUpStateField: {state}, DefaultTokenField: {"v2code"}})
+	req.URL, _ = url.Parse("/comments?page=2")
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func (s *StepUpSuite) TestStepUp(c *C) {
+	var replayed *http.Request
+	var body string
+	var rejected error
+	handler := s.stepUp.Middleware(
+		WithTokenSource(JSONToken(DefaultTokenField)),
+		WithRejectHandler(func(w http.ResponseWriter, req *http.Request, err error) {
+			rejected = err
+			w.WriteHeader(http.StatusForbidden)
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		replayed = req
+		data, _ := ioutil.ReadAll(req.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	state := s.challenge(c, handler)
+	c.Check(replayed, IsNil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, resubmit(state, "123.123.123.123:4242"))
+	c.Assert(rec.Code, Equals, http.StatusNoContent)
+	c.Check(replayed.Method, Equals, http.MethodPost)
+	c.Check(replayed.URL.RequestURI(), Equals, "/comments?page=2")
+	c.Check(replayed.Header.Get("Content-Type"), Equals, "application/json")
+	c.Check(body, Equals, `{"comment": "hello", "g-recaptcha-response": "v3code"}`)
+	result, ok := ResultFromContext(replayed.Context())
+	c.Check(ok, Equals, true)
+	c.Check(result.Hostname, Equals, "test.com")
+
+	// another client cannot use the state
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, resubmit(state, "5.6.7.8:4242"))
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+	c.Check(rejected, Equals, ErrStepUpState)
+
+	// tampered state
+	rec = httptest.NewRecorder()
+	tampered := []byte(state)
+	tampered[20] ^= 1
+	handler.ServeHTTP(rec, resubmit(string(tampered), "123.123.123.123:4242"))
+	c.Check(rejected, Equals, ErrStepUpState)
+
+	// V2 failure
+	s.v2Client.body = `{"success": false}`
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, resubmit(state, "123.123.123.123:4242"))
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+	c.Check(errors.Is(rejected, ErrInvalidSolution), Equals, true)
+
+	// expired state
+	s.v2Client.body = `{"success": true}`
+	s.now = s.now.Add(6 * time.Minute)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, resubmit(state, "123.123.123.123:4242"))
+	c.Check(rejected, Equals, ErrStepUpState)
+}
+
+func (s *StepUpSuite) TestPassThrough(c *C) {
+	s.stepUp.V3.client = &mockBodyClient{body: v3Body("0.9")}
+	handler := s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		c.Check(req.PostForm.Get("comment"), Equals, "hello")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{"comment": {"hello"}, DefaultTokenField: {"v3code"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+
+	// bodies are streamed through unchanged whatever their size
+	s.stepUp.MaxBodyBytes = 4
+	payload := strings.Repeat("x", 1<<20)
+	handler = s.stepUp.Middleware(WithTokenSource(HeaderToken("X-Token")))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		c.Check(err, IsNil)
+		c.Check(string(body) == payload, Equals, true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest(http.MethodPut, "/upload", strings.NewReader(payload))
+	req.Header.Set("X-Token", "v3code")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+
+	// the form read by the token source is replayed too, when within MaxBodyBytes
+	s.stepUp.MaxBodyBytes = 0
+	handler = s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		c.Check(string(body), Equals, "comment=hello&g-recaptcha-response=v3code")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{"comment": {"hello"}, DefaultTokenField: {"v3code"}}))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+}
+
+func (s *StepUpSuite) TestChallengeBodyTooLarge(c *C) {
+	s.stepUp.MaxBodyBytes = 4
+	handler := s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Error("handler must not be called")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, formRequest(url.Values{"comment": {"hello"}, DefaultTokenField: {"v3code"}}))
+	c.Check(rec.Code, Equals, http.StatusRequestEntityTooLarge)
+}
+
+func (s *StepUpSuite) TestStateBoundWithV3Resolver(c *C) {
+	var err error
+	s.stepUp.V3.ClientIP, err = NewClientIPResolver("10.0.0.0/8")
+	c.Assert(err, IsNil)
+	handler := s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	proxied := func(req *http.Request, client string) *http.Request {
+		req.RemoteAddr = "10.0.0.1:4242"
+		req.Header.Set("X-Forwarded-For", client)
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, proxied(formRequest(url.Values{DefaultTokenField: {"v3code"}}), "1.2.3.4"))
+	c.Assert(rec.Code, Equals, http.StatusOK)
+	match := stateRe.FindStringSubmatch(rec.Body.String())
+	c.Assert(match, HasLen, 2)
+
+	// the V2 instance does not trust the proxy, the state is still bound to the client
+	rec = httptest.NewRecorder()
+	req := proxied(formRequest(url.Values{StepUpStateField: {match[1]}, DefaultTokenField: {"v2code"}}), "5.6.7.8")
+	handler.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusForbidden)
+
+	rec = httptest.NewRecorder()
+	req = proxied(formRequest(url.Values{StepUpStateField: {match[1]}, DefaultTokenField: {"v2code"}}), "1.2.3.4")
+	handler.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+}
+
+func multipartRequest(c *C, size int) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	c.Assert(writer.WriteField(DefaultTokenField, "v3code"), IsNil)
+	part, err := writer.CreateFormFile("upload", "upload.bin")
+	c.Assert(err, IsNil)
+	part.Write(bytes.Repeat([]byte("x"), size))
+	c.Assert(writer.Close(), IsNil)
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func (s *StepUpSuite) TestBoundedRecording(c *C) {
+	s.stepUp.V3.client = &mockBodyClient{body: v3Body("0.9")}
+	s.stepUp.MaxBodyBytes = 1024
+	var recorder *bodyRecorder
+	handler := s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recorder, _ = req.Context().Value(stepUpBodyKey{}).(*bodyRecorder)
+		c.Check(req.FormValue(DefaultTokenField), Equals, "v3code")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, multipartRequest(c, 1<<20))
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+	c.Assert(recorder, NotNil)
+	c.Check(recorder.overflowed, Equals, true)
+	c.Check(recorder.read.Len(), Equals, 0)
+
+	// a header token leaves the upload unread until the handler streams it
+	handler = s.stepUp.Middleware(WithTokenSource(HeaderToken("X-Token")))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recorder, _ = req.Context().Value(stepUpBodyKey{}).(*bodyRecorder)
+		c.Check(recorder.read.Len(), Equals, 0)
+		body, _ := ioutil.ReadAll(req.Body)
+		c.Check(len(body) > 1<<20, Equals, true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := multipartRequest(c, 1<<20)
+	req.Header.Set("X-Token", "v3code")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, http.StatusNoContent)
+}
+
+func (s *StepUpSuite) TestChallengeOverflowed(c *C) {
+	s.stepUp.MaxBodyBytes = 1024
+	handler := s.stepUp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c.Error("handler must not be called")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, multipartRequest(c, 1<<20))
+	c.Check(rec.Code, Equals, http.StatusRequestEntityTooLarge)
+}
@@ -0,0 +1,59 @@
+package recaptcha
+
+import (
+	"context"
+	"net/http"
+)
+
+// SpanName name of the span started for each verification
+const SpanName = "recaptcha.verify"
+
+// Attributes set on the verification spans
+const (
+	AttributeVersion  = "recaptcha.version"
+	AttributeAction   = "recaptcha.action"
+	AttributeScore    = "recaptcha.score"
+	AttributeHostname = "recaptcha.hostname"
+	AttributeReason   = "recaptcha.reason"
+	AttributeAttempts = "recaptcha.attempts"
+)
+
+// Tracer starts the spans of verifications, implement it to bridge your tracing library
+type Tracer interface {
+	// Start starts a span child of the one in ctx, if any, and returns a context holding it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject adds the trace context held by ctx to the headers of an outgoing request.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span a unit of traced work
+type Span interface {
+	// SetAttribute records a string, int or float64 value on the span.
+	SetAttribute(key string, value interface{})
+	// End ends the span, err is the error the work failed with, if any.
+	End(err error)
+}
+
+// NoopTracer is a Tracer recording nothing, it is used when ReCAPTCHA.Tracer is nil
+var NoopTracer Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Inject(ctx context.Context, header http.Header) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) End(err error) {}
+
+func (r *ReCAPTCHA) tracer() Tracer {
+	if r.Tracer == nil {
+		return NoopTracer
+	}
+	return r.Tracer
+}
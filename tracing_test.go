@@ -0,0 +1,71 @@
+package recaptcha
+
+import (
+	"context"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+type TracingSuite struct{}
+
+var _ = Suite(&TracingSuite{})
+
+type spanKey struct{}
+
+type mockSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (m *mockSpan) SetAttribute(key string, value interface{}) { m.attributes[key] = value }
+
+func (m *mockSpan) End(err error) { m.err, m.ended = err, true }
+
+type mockTracer struct {
+	spans []*mockSpan
+}
+
+func (m *mockTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &mockSpan{name: name, attributes: map[string]interface{}{}}
+	m.spans = append(m.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (m *mockTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanKey{}).(*mockSpan); ok {
+		header.Set("Traceparent", span.name)
+	}
+}
+
+func (s *TracingSuite) TestTracer(c *C) {
+	client := &mockBodyClient{body: v3Body("0.3")}
+	tracer := &mockTracer{}
+	captcha := ReCAPTCHA{client: client, Version: V3, Tracer: tracer}
+
+	_, err := captcha.Check("mycode", VerifyOption{Threshold: 0.5})
+	c.Check(err, NotNil)
+	c.Assert(tracer.spans, HasLen, 1)
+	span := tracer.spans[0]
+	c.Check(span.name, Equals, SpanName)
+	c.Check(span.ended, Equals, true)
+	c.Check(span.err, Equals, err)
+	c.Check(span.attributes, DeepEquals, map[string]interface{}{
+		AttributeVersion:  "v3",
+		AttributeAction:   "comment",
+		AttributeScore:    float64(float32(0.3)),
+		AttributeHostname: "",
+		AttributeReason:   "score_below_threshold",
+		AttributeAttempts: 1,
+	})
+	c.Check(client.request.Header.Get("Traceparent"), Equals, SpanName)
+}
+
+func (s *TracingSuite) TestNoopTracer(c *C) {
+	captcha := ReCAPTCHA{client: &mockBodyClient{body: v3Body("0.9")}, Version: V3}
+	c.Check(captcha.tracer(), Equals, NoopTracer)
+	_, err := captcha.Check("mycode", VerifyOption{})
+	c.Check(err, IsNil)
+}
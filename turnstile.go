@@ -0,0 +1,101 @@
+package recaptcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const turnstileLink = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+type turnstileResponse struct {
+	Success     bool      `json:"success"`
+	ChallengeTS time.Time `json:"challenge_ts"`
+	Hostname    string    `json:"hostname,omitempty"`
+	Action      string    `json:"action,omitempty"`
+	CData       string    `json:"cdata,omitempty"`
+	ErrorCodes  []string  `json:"error-codes,omitempty"`
+}
+
+// Turnstile Cloudflare Turnstile verifier, see https://developers.cloudflare.com/turnstile/get-started/server-side-validation/
+type Turnstile struct {
+	client        netClient
+	Secret        string
+	TurnstileLink string
+	Timeout       time.Duration
+	horloge       clock
+}
+
+// NewTurnstile new Turnstile instance, get your secret from the Turnstile section of the Cloudflare dashboard
+func NewTurnstile(turnstileSecret string, timeout time.Duration) (Turnstile, error) {
+	if turnstileSecret == "" {
+		return Turnstile{}, fmt.Errorf("turnstile secret cannot be blank")
+	}
+	return Turnstile{
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		horloge:       &realClock{},
+		Secret:        turnstileSecret,
+		TurnstileLink: turnstileLink,
+		Timeout:       timeout,
+	}, nil
+}
+
+// Verify returns `nil` if no error and the client solved the challenge correctly
+func (t *Turnstile) Verify(challengeResponse string) error {
+	return t.VerifyContext(context.Background(), challengeResponse)
+}
+
+// VerifyContext is like Verify but the request to Turnstile is bound to ctx
+func (t *Turnstile) VerifyContext(ctx context.Context, challengeResponse string) error {
+	_, err := t.CheckContext(ctx, challengeResponse, VerifyOption{})
+	return err
+}
+
+// VerifyWithOptions returns `nil` if no error and the client solved the challenge correctly and all options are matching.
+// `Threshold` and `ApkPackageName` are ignored as Turnstile does not return them,
+// `IdempotencyKey` lets the same challenge response be verified again when retrying
+func (t *Turnstile) VerifyWithOptions(challengeResponse string, options VerifyOption) error {
+	return t.VerifyWithOptionsContext(context.Background(), challengeResponse, options)
+}
+
+// VerifyWithOptionsContext is like VerifyWithOptions but the request to Turnstile is bound to ctx
+func (t *Turnstile) VerifyWithOptionsContext(ctx context.Context, challengeResponse string, options VerifyOption) error {
+	_, err := t.CheckContext(ctx, challengeResponse, options)
+	return err
+}
+
+// Check is like VerifyWithOptions but also returns the decoded Turnstile response
+func (t *Turnstile) Check(challengeResponse string, options VerifyOption) (VerifyResult, error) {
+	return t.CheckContext(context.Background(), challengeResponse, options)
+}
+
+// CheckContext is like Check but the request to Turnstile is bound to ctx
+func (t *Turnstile) CheckContext(ctx context.Context, challengeResponse string, options VerifyOption) (Result VerifyResult, Err error) {
+	formValues := url.Values{"secret": {t.Secret}, "response": {challengeResponse}}
+	if options.RemoteIP != "" {
+		formValues.Set("remoteip", options.RemoteIP)
+	}
+	if options.IdempotencyKey != "" {
+		formValues.Set("idempotency_key", options.IdempotencyKey)
+	}
+	var result turnstileResponse
+	Err = postForm(ctx, t.client, "turnstile", t.TurnstileLink, formValues, &result)
+	if Err != nil {
+		return
+	}
+	Result = VerifyResult{
+		Success:     result.Success,
+		ChallengeTS: result.ChallengeTS,
+		Hostname:    result.Hostname,
+		Action:      result.Action,
+		CData:       result.CData,
+		ErrorCodes:  result.ErrorCodes,
+	}
+	options.ApkPackageName = ""
+	Err = checkResponse(&Result, options, t.horloge, false)
+	return
+}
@@ -0,0 +1,83 @@
+package recaptcha
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type TurnstileSuite struct{}
+
+var _ = Suite(&TurnstileSuite{})
+
+func (s *TurnstileSuite) TestNewTurnstile(c *C) {
+	captcha, err := NewTurnstile("my secret", 10*time.Second)
+	c.Assert(err, IsNil)
+	c.Check(captcha.Secret, Equals, "my secret")
+	c.Check(captcha.TurnstileLink, Equals, turnstileLink)
+	c.Check(captcha.Timeout, Equals, 10*time.Second)
+
+	_, err = NewTurnstile("", 10*time.Second)
+	c.Check(err, NotNil)
+}
+
+func (s *TurnstileSuite) TestVerify(c *C) {
+	client := &mockBodyClient{body: `
+	{
+		"success": true,
+		"challenge_ts": "2018-03-06T03:41:29.000Z",
+		"hostname": "test.com",
+		"action": "login",
+		"cdata": "session-42",
+		"error-codes": [],
+		"metadata": {"interactive": false}
+	}`}
+	var captcha Verifier = &Turnstile{
+		client:        client,
+		Secret:        "my secret",
+		TurnstileLink: turnstileLink,
+		horloge:       &mockClockWithinRespenseTime{},
+	}
+
+	result, err := captcha.Check("mycode", VerifyOption{
+		Hostname:       "test.com",
+		Action:         "login",
+		CData:          "session-42",
+		ResponseTime:   5 * time.Second,
+		RemoteIP:       "123.123.123.123",
+		IdempotencyKey: "f2b1c0e4-9a6d-4c3b-8e2f-7d5a1b3c9e0f",
+		Threshold:      0.9,
+		ApkPackageName: "ignored",
+	})
+	c.Assert(err, IsNil)
+	c.Check(result.CData, Equals, "session-42")
+	c.Check(result.Passed(CheckAction), Equals, true)
+	c.Check(result.Passed(CheckCData), Equals, true)
+	c.Check(client.request.URL.String(), Equals, turnstileLink)
+	c.Assert(client.request.ParseForm(), IsNil)
+	c.Check(client.request.PostForm.Get("secret"), Equals, "my secret")
+	c.Check(client.request.PostForm.Get("response"), Equals, "mycode")
+	c.Check(client.request.PostForm.Get("remoteip"), Equals, "123.123.123.123")
+	c.Check(client.request.PostForm.Get("idempotency_key"), Equals, "f2b1c0e4-9a6d-4c3b-8e2f-7d5a1b3c9e0f")
+
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{Action: "signup"})
+	c.Check(errors.Is(err, ErrActionMismatch), Equals, true)
+	c.Check(err, ErrorMatches, "invalid response action 'login', while expecting 'signup'")
+
+	err = captcha.VerifyWithOptions("mycode", VerifyOption{CData: "session-43"})
+	c.Check(errors.Is(err, ErrCDataMismatch), Equals, true)
+	c.Check(err, ErrorMatches, "invalid response cdata 'session-42', while expecting 'session-43'")
+
+	client.body = `{"success": false, "error-codes": ["timeout-or-duplicate"]}`
+	err = captcha.Verify("mycode")
+	c.Check(errors.Is(err, ErrRemoteErrorCodes), Equals, true)
+}
+
+func (s *TurnstileSuite) TestReCAPTCHAIgnoresCData(c *C) {
+	captcha := ReCAPTCHA{
+		client:  &mockV3SuccessClientWithActionOption{},
+		Version: V3,
+	}
+	c.Check(captcha.VerifyWithOptions("mycode", VerifyOption{CData: "session-42"}), IsNil)
+}
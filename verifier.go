@@ -0,0 +1,163 @@
+package recaptcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Verifier verifies challenge responses, it is implemented by every supported provider
+// so call sites do not depend on which one is in use
+type Verifier interface {
+	Verify(challengeResponse string) error
+	VerifyContext(ctx context.Context, challengeResponse string) error
+	VerifyWithOptions(challengeResponse string, options VerifyOption) error
+	VerifyWithOptionsContext(ctx context.Context, challengeResponse string, options VerifyOption) error
+	Check(challengeResponse string, options VerifyOption) (VerifyResult, error)
+	CheckContext(ctx context.Context, challengeResponse string, options VerifyOption) (VerifyResult, error)
+}
+
+var (
+	_ Verifier = (*ReCAPTCHA)(nil)
+	_ Verifier = (*HCaptcha)(nil)
+	_ Verifier = (*Turnstile)(nil)
+	_ Verifier = (*Enterprise)(nil)
+)
+
+// postForm posts formValues to link and decodes the json answer into result, provider names the service in error messages
+func postForm(ctx context.Context, client netClient, provider string, link string, formValues url.Values, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, link, strings.NewReader(formValues.Encode()))
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("couldn't create %s request: '%s'", provider, err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := client.Do(request)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("error posting to %s endpoint: '%s'", provider, err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	defer response.Body.Close()
+	resultBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("couldn't read response body: '%s'", err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &Error{msg: fmt.Sprintf("%s endpoint answered with status %d", provider, response.StatusCode), RequestError: true, Reason: ReasonTransport, StatusCode: response.StatusCode}
+	}
+	err = json.Unmarshal(resultBody, result)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("invalid response body json: '%s'", err), RequestError: true, Reason: ReasonDecode, err: err}
+	}
+	return nil
+}
+
+// postJSON posts body as json to link and decodes the json answer into result, provider names the service in error messages.
+// Unlike siteverify endpoints, json APIs report failures with an http error status and a google api error body
+func postJSON(ctx context.Context, client netClient, provider string, link string, body interface{}, result interface{}) error {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("couldn't encode %s request: '%s'", provider, err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, link, bytes.NewReader(requestBody))
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("couldn't create %s request: '%s'", provider, err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("error posting to %s endpoint: '%s'", provider, err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	defer response.Body.Close()
+	resultBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("couldn't read response body: '%s'", err), RequestError: true, Reason: ReasonTransport, err: err}
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(resultBody, &apiError)
+		return &Error{msg: fmt.Sprintf("%s endpoint answered with status %d: '%s'", provider, response.StatusCode, apiError.Error.Message), RequestError: true, Reason: ReasonTransport, StatusCode: response.StatusCode}
+	}
+	err = json.Unmarshal(resultBody, result)
+	if err != nil {
+		return &Error{msg: fmt.Sprintf("invalid response body json: '%s'", err), RequestError: true, Reason: ReasonDecode, err: err}
+	}
+	return nil
+}
+
+// checkResponse fails on remote error codes and unsolved challenges before running checkOptions,
+// result.Decision is set to DecisionAllow when every check passed
+func checkResponse(result *VerifyResult, options VerifyOption, horloge clock, scored bool) error {
+	if len(result.ErrorCodes) > 0 {
+		return &Error{msg: fmt.Sprintf("remote error codes: %v", result.ErrorCodes), ErrorCodes: result.ErrorCodes, Reason: ReasonRemoteErrorCodes}
+	}
+
+	if !result.Success && options.RemoteIP != "" {
+		return &Error{msg: fmt.Sprintf("invalid challenge solution or remote IP"), Reason: ReasonInvalidSolution}
+	}
+
+	if !result.Success {
+		return &Error{msg: fmt.Sprintf("invalid challenge solution"), Reason: ReasonInvalidSolution}
+	}
+
+	err := checkOptions(result, options, horloge, scored)
+	if err == nil {
+		result.Decision = DecisionAllow
+	}
+	return err
+}
+
+// checkOptions runs every check requested in options against result, recording each outcome in result.Checks.
+// The returned error describes the first failed check, `Threshold` is only checked when scored is true,
+// providers clear the options they do not support before calling it
+func checkOptions(result *VerifyResult, options VerifyOption, horloge clock, scored bool) (Err error) {
+	Err = nil
+	check := func(name string, passed bool, expected, received string, reason Reason, msg string) {
+		result.Checks = append(result.Checks, CheckResult{Name: name, Passed: passed, Expected: expected, Received: received})
+		if !passed && Err == nil {
+			Err = &Error{msg: msg, Reason: reason}
+		}
+	}
+
+	if options.Hostname != "" {
+		check(CheckHostname, options.Hostname == result.Hostname, options.Hostname, result.Hostname, ReasonHostnameMismatch,
+			fmt.Sprintf("invalid response hostname '%s', while expecting '%s'", result.Hostname, options.Hostname))
+	}
+
+	if options.ApkPackageName != "" {
+		check(CheckApkPackageName, options.ApkPackageName == result.ApkPackageName, options.ApkPackageName, result.ApkPackageName, ReasonApkPackageNameMismatch,
+			fmt.Sprintf("invalid response ApkPackageName '%s', while expecting '%s'", result.ApkPackageName, options.ApkPackageName))
+	}
+
+	if options.ResponseTime != 0 {
+		duration := horloge.Since(result.ChallengeTS)
+		check(CheckResponseTime, options.ResponseTime >= duration, options.ResponseTime.String(), duration.String(), ReasonResponseTooSlow,
+			fmt.Sprintf("time spent in resolving challenge '%fs', while expecting maximum '%fs'", duration.Seconds(), options.ResponseTime.Seconds()))
+	}
+	if options.Action != "" {
+		check(CheckAction, options.Action == result.Action, options.Action, result.Action, ReasonActionMismatch,
+			fmt.Sprintf("invalid response action '%s', while expecting '%s'", result.Action, options.Action))
+	}
+
+	if options.CData != "" {
+		check(CheckCData, options.CData == result.CData, options.CData, result.CData, ReasonCDataMismatch,
+			fmt.Sprintf("invalid response cdata '%s', while expecting '%s'", result.CData, options.CData))
+	}
+
+	if scored {
+		threshold := options.Threshold
+		if threshold == 0 {
+			threshold = DefaultThreshold
+		}
+		check(CheckThreshold, threshold <= result.Score, fmt.Sprintf("%f", threshold), fmt.Sprintf("%f", result.Score), ReasonScoreBelowThreshold,
+			fmt.Sprintf("received score '%f', while expecting minimum '%f'", result.Score, threshold))
+	}
+	return
+}